@@ -1,13 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
-	"github.com/molotovtv/go-astitools/context"
-	"github.com/molotovtv/go-astitools/flag"
 	"github.com/molotovtv/go-ftp"
 	log "github.com/molotovtv/go-logger"
 )
@@ -21,7 +21,7 @@ var (
 func main() {
 	log.Setup("go-ftp")
 	// Get subcommand
-	s := astiflag.Subcommand()
+	s := subcommand()
 	flag.Parse()
 
 	// Init ftp
@@ -30,35 +30,43 @@ func main() {
 	// Log
 	log.Debugf("Subcommand is %s", s)
 
-	// Init canceller
-	var c = asticontext.NewCanceller()
+	// Init context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Handle signals
-	handleSignals(c)
+	handleSignals(cancel)
 
 	// Switch on subcommand
 	switch s {
 	case "download":
-		var ctx, _ = c.NewContext()
 		if err := f.Download(ctx, *inputPath, *outputPath); err != nil {
 			log.Fatal(err)
 		}
 	case "upload":
-		var ctx, _ = c.NewContext()
 		if err := f.Upload(ctx, *inputPath, *outputPath); err != nil {
 			log.Fatal(err)
 		}
 	}
 }
 
+// subcommand returns the subcommand and removes it from the args so that flags can be parsed
+func subcommand() (s string) {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		s = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	return
+}
+
 // handleSignals handles signals
-func handleSignals(c *asticontext.Canceller) {
+func handleSignals(cancel context.CancelFunc) {
 	ch := make(chan os.Signal, 1)
 	signal.Notify(ch, syscall.SIGABRT, syscall.SIGKILL, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
 	go func() {
 		for s := range ch {
 			log.Debugf("Received signal %s", s)
-			c.Cancel()
+			cancel()
 		}
 	}()
 }
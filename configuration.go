@@ -1,7 +1,10 @@
 package ftp
 
 import (
+	"crypto/tls"
 	"flag"
+	"fmt"
+	"regexp"
 	"time"
 )
 
@@ -15,10 +18,53 @@ var (
 
 // Configuration represents the FTP configuration
 type Configuration struct {
-	Addr     string        `json:"addr"`
-	Password string        `json:"password"`
-	Timeout  time.Duration `toml:"timeout"`
-	Username string        `json:"username"`
+	Addr                   string                   `json:"addr"`
+	BannerPattern          string                   `json:"banner_pattern"`
+	CwdBeforeTransfer      bool                     `json:"cwd_before_transfer"`
+	DataConnTimeout        time.Duration            `json:"data_conn_timeout"`
+	DisableEPSV            bool                     `json:"disable_epsv"`
+	DisableMLSD            bool                     `json:"disable_mlsd"`
+	DisableSize            bool                     `json:"disable_size"`
+	DisableUTF8            bool                     `json:"disable_utf8"`
+	DualStackFallbackDelay time.Duration            `json:"dual_stack_fallback_delay"`
+	FallbackCredentials    []Credentials            `json:"fallback_credentials"`
+	ListCacheTTL           time.Duration            `json:"list_cache_ttl"`
+	LockStaleAfter         time.Duration            `json:"lock_stale_after"`
+	LoginTimeout           time.Duration            `json:"login_timeout"`
+	MaxConcurrentOps       int                      `json:"max_concurrent_ops"`
+	MaxDownloadSize        int64                    `json:"max_download_size"`
+	MaxUploadSize          int64                    `json:"max_upload_size"`
+	Password               string                   `json:"password"`
+	PoolMinIdle            int                      `json:"pool_min_idle"`
+	PoolSize               int                      `json:"pool_size"`
+	PoolWarmUp             bool                     `json:"pool_warm_up"`
+	Quirks                 string                   `json:"quirks"`
+	Retry                  RetryPolicy              `json:"retry"`
+	Root                   string                   `json:"root"`
+	SlowThreshold          time.Duration            `json:"slow_threshold"`
+	SlowThresholds         map[string]time.Duration `json:"slow_thresholds"`
+	TLS                    string                   `json:"tls"`
+	TLSConfig              *tls.Config              `json:"-"`
+	TLSSessionReuse        bool                     `json:"tls_session_reuse"`
+	Timeout                time.Duration            `toml:"timeout"`
+	TransferTimeout        time.Duration            `json:"transfer_timeout"`
+	TrashDir               string                   `json:"trash_dir"`
+	Username               string                   `json:"username"`
+}
+
+// validate checks the fields New would panic on: the quirks preset and the banner pattern
+func (c Configuration) validate() (err error) {
+	if c.Quirks != "" {
+		if _, err = c.withQuirks(); err != nil {
+			return
+		}
+	}
+	if c.BannerPattern != "" {
+		if _, err = regexp.Compile(c.BannerPattern); err != nil {
+			return fmt.Errorf("ftp: invalid banner pattern: %s", err)
+		}
+	}
+	return
 }
 
 // FlagConfig generates a Configuration based on flags
@@ -30,3 +76,9 @@ func FlagConfig() Configuration {
 		Username: *Username,
 	}
 }
+
+// Credentials represents a set of credentials
+type Credentials struct {
+	Password string `json:"password"`
+	Username string `json:"username"`
+}
@@ -1,38 +1,104 @@
 package ftp
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jlaffaye/ftp"
 	astilog "github.com/molotovtv/go-astilog"
-	astiio "github.com/molotovtv/go-astitools/io"
 	log "github.com/molotovtv/go-logger"
 )
 
 // FTP represents an FTP
 type FTP struct {
-	Addr     string
-	Password string
-	Timeout  time.Duration
-	Username string
-	dialer   Dialer
+	Addr                string
+	BannerPattern       string
+	CwdBeforeTransfer   bool
+	DisableSize         bool
+	FallbackCredentials []Credentials
+	ListCacheTTL        time.Duration
+	LockStaleAfter      time.Duration
+	LoginTimeout        time.Duration
+	MaxDownloadSize     int64
+	MaxUploadSize       int64
+	OnSlowOperation     func(op, path string, elapsed time.Duration)
+	Password            string
+	Retry               RetryPolicy
+	Root                string
+	SlowThreshold       time.Duration
+	SlowThresholds      map[string]time.Duration
+	Timeout             time.Duration
+	TrashDir            string
+	Username            string
+	dialer              Dialer
+	banner              string
+	bannerRe            *regexp.Regexp
+	features            map[string]string
+	lastCredentials     *Credentials
+	listCache           map[string]listCacheEntry
+	lm                  sync.Mutex
+	m                   sync.Mutex
+	pool                *pool
+	sem                 chan struct{}
 }
 
 // New creates a new FTP connection based on a configuration
+// It panics if the quirks preset is unknown or if the banner pattern is invalid, configurations being validated
+// beforehand by Profile.Configuration and Registry.Get
 func New(c Configuration, dialer Dialer) *FTP {
-	return &FTP{
-		Addr:     c.Addr,
-		Password: c.Password,
-		Timeout:  c.Timeout,
-		Username: c.Username,
-		dialer:   dialer,
+	if c.Quirks != "" {
+		var err error
+		if c, err = c.withQuirks(); err != nil {
+			panic(err)
+		}
+	}
+	if d, ok := dialer.(*defaultDialer); ok {
+		dialer = d.configure(c)
+	}
+	f := &FTP{
+		Addr:                c.Addr,
+		BannerPattern:       c.BannerPattern,
+		CwdBeforeTransfer:   c.CwdBeforeTransfer,
+		DisableSize:         c.DisableSize,
+		FallbackCredentials: c.FallbackCredentials,
+		ListCacheTTL:        c.ListCacheTTL,
+		LockStaleAfter:      c.LockStaleAfter,
+		LoginTimeout:        c.LoginTimeout,
+		MaxDownloadSize:     c.MaxDownloadSize,
+		MaxUploadSize:       c.MaxUploadSize,
+		Password:            c.Password,
+		Retry:               c.Retry,
+		Root:                c.Root,
+		SlowThreshold:       c.SlowThreshold,
+		SlowThresholds:      c.SlowThresholds,
+		Timeout:             c.Timeout,
+		TrashDir:            c.TrashDir,
+		Username:            c.Username,
+		dialer:              dialer,
+	}
+	if c.BannerPattern != "" {
+		var err error
+		if f.bannerRe, err = regexp.Compile(c.BannerPattern); err != nil {
+			panic(fmt.Errorf("ftp: invalid banner pattern: %s", err))
+		}
+	}
+	if c.MaxConcurrentOps > 0 {
+		f.sem = make(chan struct{}, c.MaxConcurrentOps)
 	}
+	if c.PoolSize > 0 {
+		f.pool = newPool(c.PoolSize, c.PoolMinIdle, c.PoolWarmUp)
+		f.pool.warmUp(f)
+	}
+	return f
 }
 
 // Connect connects to the FTP and logs in
@@ -42,8 +108,109 @@ func (f *FTP) Connect() (conn ServerConnexion, err error) {
 	log.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpConnect, f.Addr, time.Since(now))
 	}(time.Now())
 
+	// Log in with the configured credentials first, then with the fallback ones as long as the server rejects them
+	creds := f.credentials()
+	for i, c := range creds {
+		if conn, err = f.login(c); err == nil || !isLoginFailed(err) || i == len(creds)-1 {
+			if err == nil {
+				f.m.Lock()
+				f.lastCredentials = &creds[i]
+				f.m.Unlock()
+			}
+			break
+		}
+		log.Warnf("[FTP] login as %s failed, trying fallback credentials : %s", c.Username, err.Error())
+	}
+	if err != nil {
+		f.setFeatures(nil)
+		return conn, err
+	}
+
+	// Change to the root folder
+	if f.Root != "" {
+		if err = conn.ChangeDir(f.Root); err != nil {
+			conn.Quit()
+			return conn, err
+		}
+	}
+	// fmt.Print(conn)
+	// os.Exit(0)
+
+	// Refresh the features cache, the server may have changed since the last connection
+	f.setFeatures(conn.Features())
+	return conn, err
+}
+
+// credentials returns the credentials to log in with, in order: the configured ones and the fallback ones, the last
+// ones that worked going first so that a rotation doesn't cost a rejected login on every connection
+func (f *FTP) credentials() []Credentials {
+	creds := append([]Credentials{{Password: f.Password, Username: f.Username}}, f.FallbackCredentials...)
+	f.m.Lock()
+	last := f.lastCredentials
+	f.m.Unlock()
+	if last == nil {
+		return creds
+	}
+	for i, c := range creds {
+		if c == *last {
+			return append(append([]Credentials{c}, creds[:i]...), creds[i+1:]...)
+		}
+	}
+	return creds
+}
+
+// checkBanner keeps the greeting of the server, and checks it against the banner pattern if any
+func (f *FTP) checkBanner(conn ServerConnexion) (err error) {
+	b, ok := conn.(banner)
+	if !ok {
+		return
+	}
+	s := b.Banner()
+	f.m.Lock()
+	f.banner = s
+	f.m.Unlock()
+
+	// Check
+	if f.BannerPattern == "" {
+		return
+	}
+	var re *regexp.Regexp
+	if re, err = f.bannerRegexp(); err != nil {
+		return
+	}
+	if !re.MatchString(s) {
+		log.Errorf("[FTP] banner of %s %q doesn't match %q", f.Addr, s, f.BannerPattern)
+		return ErrUnexpectedBanner
+	}
+	return
+}
+
+// bannerRegexp returns the compiled banner pattern, which is only compiled again if the pattern was changed after New
+func (f *FTP) bannerRegexp() (re *regexp.Regexp, err error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.bannerRe == nil || f.bannerRe.String() != f.BannerPattern {
+		if f.bannerRe, err = regexp.Compile(f.BannerPattern); err != nil {
+			return
+		}
+	}
+	return f.bannerRe, nil
+}
+
+// Banner returns the greeting of the server from the last connection, which is empty until a connection is made or
+// if the dialer doesn't record it
+func (f *FTP) Banner() string {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.banner
+}
+
+// login dials a new connection and logs in with a set of credentials
+// The connection is quit if the login fails since some servers close it after rejecting credentials anyway
+func (f *FTP) login(c Credentials) (conn ServerConnexion, err error) {
 	// Dial
 	if f.Timeout > 0 {
 		conn, err = f.dialer.DialTimeout(f.Addr, f.Timeout)
@@ -54,37 +221,270 @@ func (f *FTP) Connect() (conn ServerConnexion, err error) {
 		return conn, err
 	}
 
-	// Login
-	if err = conn.Login(f.Username, f.Password); err != nil {
+	// Check the greeting before sending credentials
+	if err = f.checkBanner(conn); err != nil {
 		conn.Quit()
+		return conn, err
 	}
-	// fmt.Print(conn)
-	// os.Exit(0)
 
+	// Login
+	if d, ok := conn.(deadliner); ok && f.LoginTimeout > 0 {
+		if err = d.SetDeadline(time.Now().Add(f.LoginTimeout)); err != nil {
+			conn.Quit()
+			return conn, err
+		}
+		defer d.SetDeadline(time.Time{})
+	}
+	if err = conn.Login(c.Username, c.Password); err != nil {
+		conn.Quit()
+		return conn, err
+	}
 	return conn, err
 }
 
+func (f *FTP) setFeatures(fs map[string]string) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.features = fs
+}
+
+// Features returns the features advertised by the server
+// They are cached from the last login so that it doesn't cost an extra round trip, and a connection is only
+// established if there's no cache yet
+func (f *FTP) Features() (fs map[string]string, err error) {
+	f.m.Lock()
+	fs = f.features
+	f.m.Unlock()
+	if fs != nil {
+		return
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return
+	}
+	fs = conn.Features()
+	f.release(conn, nil)
+	f.setFeatures(fs)
+	return
+}
+
+// HasFeature checks whether the server advertises a feature (MLST, MDTM, REST, etc.)
+func (f *FTP) HasFeature(name string) bool {
+	fs, err := f.Features()
+	if err != nil {
+		return false
+	}
+	_, ok := fs[strings.ToUpper(name)]
+	return ok
+}
+
+// hasFeature checks whether a connection advertises a feature, which operations holding a connection use
+func hasFeature(conn ServerConnexion, name string) bool {
+	_, ok := conn.Features()[strings.ToUpper(name)]
+	return ok
+}
+
+// restStream checks whether the server advertises REST STREAM, which is what resuming uploads requires
+func restStream(conn ServerConnexion) bool {
+	return strings.Contains(strings.ToUpper(conn.Features()["REST"]), "STREAM")
+}
+
+// downloadReader reads the decoded content of a download, and finishes the transfer and gives the connection back
+// when closed
+// Later calls to Close return the error of the first one, the connection being released only once
+type downloadReader struct {
+	conn    ServerConnexion
+	decoded io.ReadCloser
+	err     error
+	f       *FTP
+	o       sync.Once
+	resp    io.ReadCloser
+}
+
+// Read implements the io.Reader interface
+func (r *downloadReader) Read(p []byte) (int, error) {
+	return r.decoded.Read(p)
+}
+
+// Close implements the io.Closer interface
+func (r *downloadReader) Close() error {
+	r.o.Do(func() {
+		// Only the transfer tells whether the connection is still usable
+		r.err = r.decoded.Close()
+		errResp := r.resp.Close()
+		if r.err == nil {
+			r.err = errResp
+		}
+		r.f.release(r.conn, errResp)
+	})
+	return r.err
+}
+
 // DownloadReader returns the reader built from the download of a file
-func (f *FTP) DownloadReader(src string) (conn ServerConnexion, r io.ReadCloser, err error) {
+// Closing the reader finishes the transfer and releases the connection. Options are applied as the reader is read,
+// as they are by Download
+func (f *FTP) DownloadReader(src string, opts ...TransferOption) (r io.ReadCloser, err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return nil, err
+	}
+
+	// Download file
+	var resp io.ReadCloser
+	if resp, err = f.retrFrom(conn, src, 0); err != nil {
+		f.release(conn, err)
+		return nil, err
+	}
+
+	// Decode, the transfer reader not being pooled since the caller decides when it's done with it
+	o := newTransferOptions(opts)
+	var decoded io.ReadCloser
+	if decoded, err = decodeReader(&transferReader{ctx: context.Background(), o: o, r: resp, start: time.Now()}, o); err != nil {
+		resp.Close()
+		f.release(conn, err)
+		return nil, err
+	}
+	return &downloadReader{conn: conn, decoded: decoded, f: f, resp: resp}, nil
+}
+
+// DownloadPipe returns a reader streaming the download of a file, the transfer running in a goroutine that writes to
+// a pipe. Errors opening the transfer are returned right away, and later ones are returned by Read
+// The connection is released once the transfer completes, fails, or the reader is closed, which aborts the transfer
+func (f *FTP) DownloadPipe(ctx context.Context, src string, opts ...TransferOption) (io.ReadCloser, error) {
 	// Connect
-	if conn, err = f.Connect(); err != nil {
-		return conn, nil, err
+	conn, err := f.acquireContext(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Download file
+	var resp io.ReadCloser
+	if resp, err = f.retrFrom(conn, src, 0); err != nil {
+		f.release(conn, err)
+		return nil, err
+	}
+
+	// Copy
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := copyContext(ctx, pw, resp, newTransferOptions(opts))
+		if errClose := resp.Close(); err == nil {
+			err = errClose
+		}
+		f.release(conn, err)
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// transferPath returns the path to send in a transfer command
+// In CWD before transfer mode, it changes into the folder of p and returns its base name, and restore changes back
+// into the previous working directory
+func (f *FTP) transferPath(conn ServerConnexion, p string) (name string, restore func() error, err error) {
+	restore = func() error { return nil }
+	if !f.CwdBeforeTransfer || path.Dir(p) == "." {
+		return p, restore, nil
+	}
+
+	// Keep the working directory
+	var sCurrent string
+	if sCurrent, err = conn.CurrentDir(); err != nil {
+		return
+	}
+
+	// Change directory
+	if err = conn.ChangeDir(path.Dir(p)); err != nil {
+		return
+	}
+	return path.Base(p), func() error { return conn.ChangeDir(sCurrent) }, nil
+}
+
+// restoreDir restores the working directory after a transfer and keeps the transfer error if any
+func restoreDir(restore func() error, err *error) {
+	if errRestore := restore(); errRestore != nil && *err == nil {
+		*err = errRestore
+	}
+}
+
+// restoreReader restores the working directory once the transfer is closed
+type restoreReader struct {
+	io.ReadCloser
+	restore func() error
+}
+
+// Close implements the io.Closer interface
+func (r *restoreReader) Close() (err error) {
+	err = r.ReadCloser.Close()
+	restoreDir(r.restore, &err)
+	return
+}
+
+// sizeLimitReader fails with ErrFileTooLarge once more than max bytes of a file have been read, n being the offset in
+// the file
+type sizeLimitReader struct {
+	io.ReadCloser
+	max int64
+	n   int64
+}
+
+// Read implements the io.Reader interface
+func (r *sizeLimitReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	r.n += int64(n)
+	if r.n > r.max {
+		return n, ErrFileTooLarge
+	}
+	return
+}
+
+// retrFrom downloads a file starting at offset, with REST if the server supports it or by skipping the first bytes
+// otherwise
+// Every download goes through it, so it's where MaxDownloadSize is enforced
+func (f *FTP) retrFrom(conn ServerConnexion, src string, offset int64) (r io.ReadCloser, err error) {
+	var restore func() error
+	if src, restore, err = f.transferPath(conn, src); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			restoreDir(restore, &err)
+			return
+		}
+		if f.MaxDownloadSize > 0 {
+			r = &sizeLimitReader{ReadCloser: r, max: f.MaxDownloadSize, n: offset}
+		}
+		if f.CwdBeforeTransfer {
+			r = &restoreReader{ReadCloser: r, restore: restore}
+		}
+	}()
+
+	if offset == 0 {
+		return conn.Retr(src)
+	}
+	if hasFeature(conn, "REST") {
+		return conn.RetrFrom(src, uint64(offset))
+	}
 	if r, err = conn.Retr(src); err != nil {
-		return conn, nil, err
+		return
+	}
+	if _, err = io.CopyN(ioutil.Discard, r, offset); err != nil {
+		r.Close()
+		return nil, err
 	}
-	return conn, r, nil
+	return
 }
 
 // Download downloads a file from the remote server
-func (f *FTP) Download(ctx context.Context, src, dst string) (err error) {
+func (f *FTP) Download(ctx context.Context, src, dst string, opts ...TransferOption) (err error) {
 	// Log
 	l := fmt.Sprintf("FTP download from %s to %s", src, dst)
 	log.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpDownload, src, time.Since(now))
 	}(time.Now())
 
 	// Check context error
@@ -94,20 +494,48 @@ func (f *FTP) Download(ctx context.Context, src, dst string) (err error) {
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquireContext(ctx); err != nil {
 		return
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	// Check context error
 	if err = ctx.Err(); err != nil {
 		return
 	}
+	return f.download(ctx, conn, src, dst, newTransferOptions(opts))
+}
+
+// download downloads a file from the remote server on a connection
+func (f *FTP) download(ctx context.Context, conn ServerConnexion, src, dst string, o transferOptions) (err error) {
+	// Skip identical files
+	if o.comparator != nil {
+		var same bool
+		if same, err = f.same(ctx, conn, dst, src, o.comparator); err != nil {
+			return
+		} else if same {
+			log.Debugf("%s is identical to %s, skipping download", dst, src)
+			return
+		}
+	}
+
+	// Get the size, if possible, so that short transfers can be detected
+	var size int64 = -1
+	if f.sizeSupported(conn) {
+		if n, errSize := conn.FileSize(src); errSize == nil {
+			size = n
+		}
+	}
+
+	// Check size before the transfer when possible, retrFrom checking it during the transfer otherwise
+	if f.MaxDownloadSize > 0 && size > f.MaxDownloadSize {
+		return ErrFileTooLarge
+	}
 
 	// Download file
 	var r io.ReadCloser
 	log.Debugf("Downloading %s", src)
-	if r, err = conn.Retr(src); err != nil {
+	if r, err = f.retrFrom(conn, src, 0); err != nil {
 		return
 	}
 	defer r.Close()
@@ -133,37 +561,45 @@ func (f *FTP) Download(ctx context.Context, src, dst string) (err error) {
 	// Copy to dst
 	var n int64
 	log.Debugf("Copying downloaded content to %s", dst)
-	n, err = astiio.Copy(ctx, r, dstFile)
+	n, err = copyContext(ctx, dstFile, r, o)
 	log.Debugf("Copied %dkb", n/1024)
+
+	// The data connection may have been closed early without error
+	if err == nil && size >= 0 && n != size {
+		err = ErrIncompleteTransfer
+	}
+
+	// Don't leave a partial file behind
+	if err == ErrFileTooLarge || err == ErrIncompleteTransfer {
+		dstFile.Close()
+		os.Remove(dst)
+	}
 	return
 }
 
-// Remove removes a file
+// Remove removes a file, or moves it to the trash folder if there's one
 func (f *FTP) Remove(src string) (err error) {
 	// Log
 	l := fmt.Sprintf("FTP Remove of %s", src)
 	log.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpRemove, src, time.Since(now))
 	}(time.Now())
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	// Remove
-	log.Debugf("Removing %s", src)
-	if err = conn.Delete(src); err != nil {
-		return
-	}
-	return
+	return f.remove(conn, src)
 }
 
 // Upload uploads a source path content to a destination
-func (f *FTP) Upload(ctx context.Context, src, dst string) (err error) {
+func (f *FTP) Upload(ctx context.Context, src, dst string, opts ...TransferOption) (err error) {
 	// Log
 	l := fmt.Sprintf("FTP Upload to %s", dst)
 	log.Debugf("[Start] %s", l)
@@ -171,6 +607,16 @@ func (f *FTP) Upload(ctx context.Context, src, dst string) (err error) {
 		log.Debugf("[End] %s in %s", l, time.Since(now))
 	}(time.Now())
 
+	return f.upload(ctx, src, dst, opts, func() (ServerConnexion, func(error), error) {
+		conn, err := f.acquireContext(ctx)
+		return conn, func(err error) { f.release(conn, err) }, err
+	}, f.UploadReader)
+}
+
+// upload checks the size of a local file, skips it when the comparator or its checksum tells it's already on the
+// remote, sends it with send and writes its sidecar with hash dedup. The remote checks run on the connection returned
+// by acquire, which is released before the file is sent
+func (f *FTP) upload(ctx context.Context, src, dst string, opts []TransferOption, acquire func() (ServerConnexion, func(error), error), send func(ctx context.Context, r io.Reader, dst string, opts ...TransferOption) error) (err error) {
 	var srcFile *os.File
 	log.Debugf("Opening %s", src)
 	if srcFile, err = os.Open(src); err != nil {
@@ -178,25 +624,291 @@ func (f *FTP) Upload(ctx context.Context, src, dst string) (err error) {
 	}
 	defer func() { _ = srcFile.Close() }()
 
-	return f.UploadReader(ctx, srcFile, dst)
+	// The remote file can only be compared with the source when it's sent as is
+	o := newTransferOptions(opts)
+	if o.hashDedup && o.encoded() {
+		return ErrUnsupported
+	}
+
+	// Check size
+	if f.MaxUploadSize > 0 {
+		var fi os.FileInfo
+		if fi, err = srcFile.Stat(); err != nil {
+			return
+		} else if fi.Size() > f.MaxUploadSize {
+			return ErrFileTooLarge
+		}
+	}
+
+	// Local checksum, computed before a connection is held
+	var sum string
+	var size int64
+	if o.hashDedup {
+		if sum, size, err = localSum(ctx, srcFile); err != nil {
+			return
+		}
+	}
+
+	// Skip files that are already on the remote
+	if o.comparator != nil || o.hashDedup {
+		var conn ServerConnexion
+		var release func(error)
+		if conn, release, err = acquire(); err != nil {
+			return
+		}
+		var same bool
+		if o.comparator != nil {
+			same, err = f.same(ctx, conn, src, dst, o.comparator)
+		}
+		if err == nil && !same && o.hashDedup {
+			same, err = f.sameSum(conn, dst, sum, size)
+		}
+		release(err)
+		if err != nil {
+			return
+		} else if same {
+			log.Debugf("%s is already on the remote as %s, skipping upload", src, dst)
+			return
+		}
+	}
+
+	if err = send(ctx, srcFile, dst, opts...); err != nil || !o.hashDedup {
+		return
+	}
+
+	// Write the sidecar
+	return send(ctx, strings.NewReader(sidecar(sum, dst)), dst+sidecarSuffix)
+}
+
+// ResumeUpload uploads a source path content to a destination, continuing where a previous attempt stopped
+// If the server advertises REST STREAM, the source is sent from the current size of the destination with REST and
+// STOR, which keeps the bytes already sent at their exact offsets. Otherwise the whole source is sent again
+// Offsets in the destination don't match offsets in the source once it's compressed, transformed or encrypted, so
+// those options fail with ErrUnsupported
+func (f *FTP) ResumeUpload(ctx context.Context, src, dst string, opts ...TransferOption) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP resume upload to %s", dst)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpUpload, dst, time.Since(now))
+	}(time.Now())
+
+	o := newTransferOptions(opts)
+	if o.encoded() {
+		return ErrUnsupported
+	}
+
+	var srcFile *os.File
+	log.Debugf("Opening %s", src)
+	if srcFile, err = os.Open(src); err != nil {
+		return
+	}
+	defer srcFile.Close()
+
+	o.maxSize = f.MaxUploadSize
+	return f.stor(ctx, srcFile, dst, true, 0, o)
 }
 
 // UploadReader uploads a reader content to a destination
-func (f *FTP) UploadReader(ctx context.Context, reader io.Reader, dst string) error {
-	conn, err := f.Connect()
+// If the reader can seek, transfers aborted by the server (426) or by a connection error are retried on a new
+// connection according to the retry policy of the instance, 3 attempts by default, which can be overridden with
+// WithRetryPolicy or WithNoRetry
+func (f *FTP) UploadReader(ctx context.Context, reader io.Reader, dst string, opts ...TransferOption) (err error) {
+	defer func(now time.Time) {
+		f.checkSlow(OpUpload, dst, time.Since(now))
+	}(time.Now())
 
-	if err != nil {
+	o := newTransferOptions(opts)
+	o.maxSize = f.MaxUploadSize
+
+	// Compress, transform and encrypt
+	var closers []io.Closer
+	defer func() { closeAll(closers) }()
+	if reader, dst, closers, err = encodeUpload(reader, dst, o); err != nil {
+		return
+	}
+
+	// Only a reader that can seek can be sent again
+	seeker, canRetry := reader.(io.Seeker)
+	var start int64
+	if canRetry {
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			canRetry, err = false, nil
+		}
+	}
+
+	// Retry policy
+	policy := f.retryPolicy(o)
+
+	for attempt := 0; ; attempt++ {
+		if err = f.stor(ctx, reader, dst, attempt > 0, start, o); err == nil || !canRetry || !retryable(err) || attempt+1 >= policy.Attempts || ctx.Err() != nil {
+			return
+		}
+
+		// Wait
+		log.Debugf("[FTP] retrying upload to %s (attempt %d/%d) after error : %s", dst, attempt+2, policy.Attempts, err.Error())
+		select {
+		case <-time.After(policy.Delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// encodeUpload compresses, transforms and encrypts the content of an upload according to the options, and returns the
+// reader to send, the effective destination and the readers to close once the upload is done
+func encodeUpload(reader io.Reader, dst string, o transferOptions) (r io.Reader, effective string, closers []io.Closer, err error) {
+	r, effective = reader, dst
+
+	// Compress
+	if o.gzip {
+		if !strings.HasSuffix(effective, gzipSuffix) {
+			effective += gzipSuffix
+		}
+		gr := gzipReader(r)
+		closers = append(closers, gr)
+		r = gr
+	}
+
+	// Transform
+	if len(o.transforms) > 0 {
+		tr := applyTransforms(r, o.transforms)
+		closers = append(closers, tr)
+		r = tr
+	}
+
+	// Encrypt
+	if o.encryption != nil {
+		var er io.ReadCloser
+		if er, err = o.encryption.encryptReader(r); err != nil {
+			closeAll(closers)
+			return nil, "", nil, err
+		}
+		closers = append(closers, er)
+		r = er
+	}
+	return
+}
+
+// closeAll closes readers in the reverse order of their opening
+func closeAll(closers []io.Closer) {
+	for i := len(closers) - 1; i >= 0; i-- {
+		closers[i].Close()
+	}
+}
+
+// stor uploads a reader content to a destination
+// When resuming, the reader is rewound to where the remote file ends if the server supports REST STREAM, or to its
+// start otherwise
+func (f *FTP) stor(ctx context.Context, reader io.Reader, dst string, resume bool, start int64, o transferOptions) (err error) {
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return err
+	}
+	defer func() { f.release(conn, err) }()
+	return f.storConn(ctx, conn, reader, dst, resume, start, o)
+}
+
+// storConn is stor on a connection
+func (f *FTP) storConn(ctx context.Context, conn ServerConnexion, reader io.Reader, dst string, resume bool, start int64, o transferOptions) (err error) {
+	// Check context error
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	defer f.invalidateList(dst)
+
+	// Change directory if needed
+	var restore func() error
+	if dst, restore, err = f.transferPath(conn, dst); err != nil {
+		return err
+	}
+	defer restoreDir(restore, &err)
+
+	// Don't leave a partial file behind when it's too large
+	defer func() {
+		if err == ErrFileTooLarge {
+			conn.Delete(dst)
+		}
+	}()
+
+	r := newTransferReader(ctx, reader, o)
+	defer putTransferReader(r)
+	if !resume {
+		log.Debugf("Uploading to %s", dst)
+		if err = allocate(conn, reader, o); err != nil {
+			return err
+		}
+		return conn.Stor(dst, r)
+	}
+
+	// Rewind
+	seeker := reader.(io.Seeker)
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	var offset int64
+	if restStream(conn) {
+		if offset, err = f.fileSize(conn, dst); err != nil {
+			offset, err = 0, nil
+		}
+
+		// A remote file larger than the source is not a previous attempt, it's sent again
+		if size, ok := readerSize(reader); ok && offset > size {
+			offset = 0
+		}
+	}
+	if _, err = seeker.Seek(start+offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	log.Debugf("Resuming upload to %s at %d", dst, offset)
+	if err = allocate(conn, reader, o); err != nil {
+		return err
+	}
+	if offset > 0 {
+		return conn.StorFrom(dst, r, uint64(offset))
+	}
+	return conn.Stor(dst, r)
+}
+
+// AppendReader appends a reader content to a destination, creating it if it doesn't exist
+func (f *FTP) AppendReader(ctx context.Context, reader io.Reader, dst string, opts ...TransferOption) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP Append to %s", dst)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpAppend, dst, time.Since(now))
+	}(time.Now())
+
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
 		return err
 	}
-	defer func() { _ = conn.Quit() }()
+	defer func() { f.release(conn, err) }()
+	return f.appendConn(ctx, conn, reader, dst, newTransferOptions(opts))
+}
 
+// appendConn is AppendReader on a connection
+func (f *FTP) appendConn(ctx context.Context, conn ServerConnexion, reader io.Reader, dst string, o transferOptions) (err error) {
 	// Check context error
 	if err = ctx.Err(); err != nil {
 		return err
 	}
+	defer f.invalidateList(dst)
 
-	log.Debugf("Uploading to %s", dst)
-	return conn.Stor(dst, astiio.NewReader(ctx, reader))
+	// Change directory if needed
+	var restore func() error
+	if dst, restore, err = f.transferPath(conn, dst); err != nil {
+		return err
+	}
+	defer restoreDir(restore, &err)
+
+	log.Debugf("Appending to %s", dst)
+	r := newTransferReader(ctx, reader, o)
+	defer putTransferReader(r)
+	return conn.Append(dst, r)
 }
 
 // FileSize do
@@ -206,17 +918,40 @@ func (f *FTP) FileSize(src string) (s int64, err error) {
 	log.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpFileSize, src, time.Since(now))
 	}(time.Now())
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	// File size
-	return conn.FileSize(src)
+	return f.fileSize(conn, src)
+}
+
+// sizeSupported checks whether SIZE can be used, which is the case unless it's disabled or the server advertises
+// features without it
+func (f *FTP) sizeSupported(conn ServerConnexion) bool {
+	if f.DisableSize {
+		return false
+	}
+	fs := conn.Features()
+	return len(fs) == 0 || hasFeature(conn, "SIZE")
+}
+
+// fileSize returns the size of a file with SIZE, or from the listing of its folder when SIZE is disabled
+func (f *FTP) fileSize(conn ServerConnexion, p string) (int64, error) {
+	if !f.DisableSize {
+		return conn.FileSize(p)
+	}
+	e, err := f.stat(conn, p)
+	if err != nil {
+		return 0, err
+	}
+	return int64(e.Size), nil
 }
 
 // var FTPConnect = func(f *FTP) (conn *ftp.ServerConn, err error) {
@@ -231,19 +966,20 @@ func (f *FTP) List(sFolder string, aExtensionsAllowed []string, sPattern string)
 	astilog.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		astilog.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpList, sFolder, time.Since(now))
 	}(time.Now())
 
 	var aFiles, aFilesRaw []*ftp.Entry
 
 	// Connect
 	var conn ServerConnexion
-	conn, err := f.Connect()
+	conn, err := f.acquire()
 	if err != nil {
 		log.Errorf("[FTP] error : %s", err.Error())
 		return aFilesRaw
 	}
-	defer conn.Quit()
 	aFilesRaw, err = conn.List(sFolder)
+	f.release(conn, err)
 
 	if err != nil {
 		log.Errorf("[FTP] error : %s", err.Error())
@@ -293,6 +1029,103 @@ func (f *FTP) List(sFolder string, aExtensionsAllowed []string, sPattern string)
 	// return conn.ListFileSize(src)
 }
 
+// ListMany lists several folders concurrently, each worker reusing its own connection
+// Folders that can't be listed are missing from the results, and the first error met is returned along with the
+// folders that could be listed
+func (f *FTP) ListMany(ctx context.Context, aFolders []string, iConcurrency int) (map[string][]*ftp.Entry, error) {
+
+	// Log
+	l := fmt.Sprintf("FTP list of %d folders", len(aFolders))
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	if iConcurrency <= 0 {
+		iConcurrency = 1
+	}
+	if iConcurrency > len(aFolders) {
+		iConcurrency = len(aFolders)
+	}
+
+	aResults := make(map[string][]*ftp.Entry, len(aFolders))
+	var firstErr error
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	fail := func(err error) {
+		m.Lock()
+		defer m.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	chFolders := make(chan string)
+	for i := 0; i < iConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var conn ServerConnexion
+			defer func() {
+				if conn != nil {
+					f.release(conn, nil)
+				}
+			}()
+
+			for sFolder := range chFolders {
+				// Check context error
+				if err := ctx.Err(); err != nil {
+					fail(err)
+					continue
+				}
+
+				// Connect
+				if conn == nil {
+					var err error
+					if conn, err = f.acquireContext(ctx); err != nil {
+						log.Errorf("[FTP] error : %s", err.Error())
+						fail(err)
+						conn = nil
+						continue
+					}
+				}
+
+				aFilesRaw, err := conn.List(sFolder)
+				if err != nil {
+					log.Errorf("[FTP] error while listing %s : %s", sFolder, err.Error())
+					fail(fmt.Errorf("ftp: listing %s failed: %w", sFolder, err))
+					if !reusable(err) {
+						f.release(conn, err)
+						conn = nil
+					}
+					continue
+				}
+
+				var aFiles []*ftp.Entry
+				for _, oFile := range aFilesRaw {
+					if oFile.Name == "." || oFile.Name == ".." {
+						continue
+					}
+					aFiles = append(aFiles, oFile)
+				}
+
+				m.Lock()
+				aResults[sFolder] = aFiles
+				m.Unlock()
+			}
+		}()
+	}
+
+	for _, sFolder := range aFolders {
+		chFolders <- sFolder
+	}
+	close(chFolders)
+	wg.Wait()
+
+	return aResults, firstErr
+}
+
 //ListFolders do
 func (f *FTP) ListFolders(sFolder string) []*ftp.Entry {
 
@@ -301,19 +1134,20 @@ func (f *FTP) ListFolders(sFolder string) []*ftp.Entry {
 	astilog.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		astilog.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpList, sFolder, time.Since(now))
 	}(time.Now())
 
 	var aFolders, aFilesRaw []*ftp.Entry
 
 	// Connect
 	var conn ServerConnexion
-	conn, err := f.Connect()
+	conn, err := f.acquire()
 	if err != nil {
 		log.Errorf("[FTP] error : %s", err.Error())
 		return aFilesRaw
 	}
-	defer conn.Quit()
 	aFilesRaw, err = conn.List(sFolder)
+	f.release(conn, err)
 
 	if err != nil {
 		log.Errorf("[FTP] error : %s", err.Error())
@@ -354,6 +1188,21 @@ func (f *FTP) GetExtensionFile(oFile *ftp.Entry) string {
 	return strings.ToLower(sExtension)
 }
 
+// stat returns the entry of a path by listing its parent folder
+func (f *FTP) stat(conn ServerConnexion, sPath string) (*ftp.Entry, error) {
+	aEntries, err := conn.List(path.Dir(sPath))
+	if err != nil {
+		return nil, err
+	}
+	sName := path.Base(sPath)
+	for _, oEntry := range aEntries {
+		if oEntry.Name == sName {
+			return oEntry, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
 //Exists do
 func (f *FTP) Exists(sFilePath string) (b bool, err error) {
 	// Log
@@ -361,19 +1210,84 @@ func (f *FTP) Exists(sFilePath string) (b bool, err error) {
 	astilog.Debugf("[Start] %s", l)
 	defer func(now time.Time) {
 		astilog.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpExists, sFilePath, time.Since(now))
 	}(time.Now())
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return false, err
 	}
-	defer conn.Quit()
 
-	if _, err := conn.FileSize(sFilePath); err != nil {
+	b, err = f.exists(conn, sFilePath)
+	f.release(conn, err)
+	if err != nil && reusable(err) {
 		return false, nil
 	}
+	return
+}
+
+// exists checks whether a file exists with SIZE, and falls back on listing its parent folder for servers that don't
+// support SIZE. The underlying client doesn't expose MLST, but it lists with MLSD when the server supports it
+func (f *FTP) exists(conn ServerConnexion, sFilePath string) (bool, error) {
+	// SIZE, unless the server advertises features without it
+	if f.sizeSupported(conn) {
+		_, err := conn.FileSize(sFilePath)
+		if err == nil {
+			return true, nil
+		} else if !isNotImplemented(err) {
+			return false, err
+		}
+	}
 
+	// Parent folder listing
+	if _, err := f.stat(conn, sFilePath); err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ExistsDir checks whether a directory exists by changing into it, the working directory is restored afterwards
+func (f *FTP) ExistsDir(sPath string) (b bool, err error) {
+	// Log
+	l := fmt.Sprintf("FTP dir exists of %s", sPath)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpExists, sPath, time.Since(now))
+	}(time.Now())
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return false, err
+	}
+	defer func() { f.release(conn, err) }()
+	return f.existsDir(conn, sPath)
+}
+
+// existsDir checks whether a folder exists by changing into it, the working directory being restored afterwards
+func (f *FTP) existsDir(conn ServerConnexion, sPath string) (b bool, err error) {
+	// Keep the working directory
+	var sCurrent string
+	if sCurrent, err = conn.CurrentDir(); err != nil {
+		return false, err
+	}
+
+	// Change directory
+	if errCwd := conn.ChangeDir(sPath); errCwd != nil {
+		if !reusable(errCwd) {
+			return false, errCwd
+		}
+		return false, nil
+	}
+
+	// Restore the working directory
+	if err = conn.ChangeDir(sCurrent); err != nil {
+		return true, err
+	}
 	return true, nil
 }
 
@@ -382,10 +1296,10 @@ func (f *FTP) CreateDir(sPath string) (err error) {
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return err
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	return conn.MakeDir(sPath)
 }
@@ -395,10 +1309,10 @@ func (f *FTP) RemoveDir(sPath string) (err error) {
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return err
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	return conn.RemoveDir(sPath)
 }
@@ -408,10 +1322,10 @@ func (f *FTP) RemoveDirRecur(sPath string) (err error) {
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return err
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	return conn.RemoveDirRecur(sPath)
 }
@@ -421,44 +1335,46 @@ func (f *FTP) Rename(sSource string, sDestination string) (err error) {
 
 	// Connect
 	var conn ServerConnexion
-	if conn, err = f.Connect(); err != nil {
+	if conn, err = f.acquire(); err != nil {
 		return err
 	}
-	defer conn.Quit()
+	defer func() { f.release(conn, err) }()
 
 	aDestination := strings.Split(sDestination, "/")
 	sDestinationFolder := strings.Join(aDestination[:len(aDestination)-1], "/")
 
-	f.checkFolders(sDestinationFolder)
+	f.checkFolders(conn, sDestinationFolder)
 
+	defer f.invalidateList(sSource)
+	defer f.invalidateList(sDestination)
 	return conn.Rename(sSource, sDestination)
 }
 
-func (f *FTP) checkFolders(sFolder string) {
+// checkFolders creates the missing folders of a path, on the connection of the calling operation
+func (f *FTP) checkFolders(conn ServerConnexion, sFolder string) {
 
 	if len(sFolder) == 0 {
 		return
 	}
 
-	ok, err := f.Exists(sFolder)
-	if ok && err == nil {
+	if _, err := f.fileSize(conn, sFolder); err == nil {
 		return
 	}
 
 	aFolder := strings.Split(sFolder, "/")
 
 	if len(aFolder) == 2 {
-		f.CreateDir(sFolder)
+		conn.MakeDir(sFolder)
 		return
 	}
 
-	f.checkFolders(strings.Join(aFolder[:len(aFolder)-1], "/"))
-	f.CreateDir(sFolder)
+	f.checkFolders(conn, strings.Join(aFolder[:len(aFolder)-1], "/"))
+	conn.MakeDir(sFolder)
 
 }
 
 //CreateFile in folder with content in param
-func (f *FTP) CreateFile(sPath string, reader io.Reader) error {
+func (f *FTP) CreateFile(sPath string, reader io.Reader) (err error) {
 
 	if len(sPath) == 0 {
 		return nil
@@ -466,13 +1382,35 @@ func (f *FTP) CreateFile(sPath string, reader io.Reader) error {
 
 	// Connect
 	var conn ServerConnexion
-	var err error
+	if conn, err = f.acquire(); err != nil {
+		return err
+	}
+	defer func() { f.release(conn, err) }()
+	return f.createFile(conn, sPath, reader)
+}
+
+// createFile is CreateFile on a connection
+func (f *FTP) createFile(conn ServerConnexion, sPath string, reader io.Reader) (err error) {
+	defer f.invalidateList(sPath)
 
-	if conn, err = f.Connect(); err != nil {
+	// Change directory if needed
+	var restore func() error
+	if sPath, restore, err = f.transferPath(conn, sPath); err != nil {
 		return err
 	}
-	defer conn.Quit()
+	defer restoreDir(restore, &err)
 
 	return conn.Stor(sPath, reader)
+}
+
+// Touch creates an empty file, which is what marker files (.done, .ready, etc.) usually are
+func (f *FTP) Touch(sPath string) error {
+	// Log
+	l := fmt.Sprintf("FTP touch of %s", sPath)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
 
+	return f.CreateFile(sPath, bytes.NewReader(nil))
 }
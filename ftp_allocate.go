@@ -0,0 +1,54 @@
+package ftp
+
+import (
+	"io"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// allocate reserves the space needed by what's left in reader, if asked to and if possible
+func allocate(conn ServerConnexion, reader io.Reader, o transferOptions) error {
+	if !o.allocate {
+		return nil
+	}
+	a, ok := conn.(allocator)
+	if !ok {
+		log.Warnf("[FTP] connexion can't send ALLO, skipping it")
+		return nil
+	}
+	size, ok := readerSize(reader)
+	if !ok {
+		log.Warnf("[FTP] size of upload is unknown, skipping ALLO")
+		return nil
+	}
+	if err := a.Allocate(size); err == ErrUnsupported {
+		log.Warnf("[FTP] ALLO can't be sent on a control connection upgraded with AUTH TLS, skipping it")
+	} else if isNotImplemented(err) {
+		log.Warnf("[FTP] server doesn't implement ALLO, skipping it")
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// readerSize returns the number of bytes left in a reader, when it can tell
+func readerSize(r io.Reader) (int64, bool) {
+	switch v := r.(type) {
+	case interface{ Len() int }:
+		return int64(v.Len()), true
+	case io.Seeker:
+		cur, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		end, err := v.Seek(0, io.SeekEnd)
+		if err != nil {
+			return 0, false
+		}
+		if _, err = v.Seek(cur, io.SeekStart); err != nil {
+			return 0, false
+		}
+		return end - cur, true
+	}
+	return 0, false
+}
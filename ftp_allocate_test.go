@@ -0,0 +1,54 @@
+package ftp
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func TestServerConnexion_Allocate(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	go func() {
+		r := bufio.NewReader(server)
+		for _, reply := range []string{"200 ALLO command successful\r\n", "504 Not implemented for that parameter\r\n"} {
+			if l, err := r.ReadString('\n'); err != nil || l != "ALLO 10\r\n" {
+				return
+			}
+			server.Write([]byte(reply))
+		}
+	}()
+	c := &serverConnexion{control: client, r: newControlRecorder()}
+
+	if err := c.Allocate(10); err != nil {
+		t.Fatalf("serverConnexion.Allocate() error = %v", err)
+	}
+	if c.r.cmd != "ALLO" {
+		t.Errorf("recorded command = %q, want ALLO", c.r.cmd)
+	}
+	if err := c.Allocate(10); err == nil {
+		t.Fatal("serverConnexion.Allocate() should fail")
+	} else if e, ok := err.(*textproto.Error); !ok || e.Code != 504 {
+		t.Fatalf("serverConnexion.Allocate() error = %v, want 504", err)
+	}
+
+	// Explicit TLS
+	if err := (&serverConnexion{}).Allocate(10); err != ErrUnsupported {
+		t.Errorf("serverConnexion.Allocate() error = %v, want %v", err, ErrUnsupported)
+	}
+}
+
+func TestReaderSize(t *testing.T) {
+	r := strings.NewReader("0123456789")
+	r.Seek(4, 0)
+	if n, ok := readerSize(r); !ok || n != 6 {
+		t.Errorf("readerSize() = %d, %v, want 6, true", n, ok)
+	}
+	if _, ok := readerSize(struct{ io.Reader }{r}); ok {
+		t.Error("readerSize() should not know the size of a plain reader")
+	}
+}
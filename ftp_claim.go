@@ -0,0 +1,43 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// Claim renames a file to its name followed by claimSuffix, which should be specific to the consumer, and returns
+// the new path. Renames being atomic on the server, only one of several consumers reading the same inbox gets the
+// file while the others get ErrClaimed
+func (f *FTP) Claim(ctx context.Context, p, claimSuffix string) (claimed string, err error) {
+	// Log
+	l := fmt.Sprintf("FTP claim of %s", p)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	if claimSuffix == "" {
+		return "", errors.New("ftp: empty claim suffix")
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Rename
+	claimed = p + claimSuffix
+	if err = conn.Rename(p, claimed); err != nil {
+		if isFileUnavailable(err) {
+			err = ErrClaimed
+		}
+		return "", err
+	}
+	return
+}
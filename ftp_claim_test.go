@@ -0,0 +1,25 @@
+package ftp_test
+
+import (
+	"context"
+	"net/textproto"
+	"testing"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+)
+
+func TestFTP_Claim(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Rename", "/inbox/a.xml", "/inbox/a.xml.worker-1").Return(nil)
+	oConnexion.On("Rename", "/inbox/b.xml", "/inbox/b.xml.worker-1").Return(&textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such file"})
+	oFtp := NewFtp(oConnexion)
+
+	if claimed, err := oFtp.Claim(context.Background(), "/inbox/a.xml", ".worker-1"); err != nil || claimed != "/inbox/a.xml.worker-1" {
+		t.Errorf("base.Claim() = %s, %v, want /inbox/a.xml.worker-1, nil", claimed, err)
+	}
+	if _, err := oFtp.Claim(context.Background(), "/inbox/b.xml", ".worker-1"); err != ftp.ErrClaimed {
+		t.Errorf("base.Claim() error = %v, want %v", err, ftp.ErrClaimed)
+	}
+}
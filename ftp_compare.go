@@ -0,0 +1,111 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"hash"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// Comparison holds what is known about a local file and its remote counterpart
+type Comparison struct {
+	Local      os.FileInfo
+	LocalPath  string
+	Remote     *ftp.Entry
+	RemotePath string
+	open       func() (io.ReadCloser, error)
+}
+
+// OpenRemote downloads the remote file. The reader must be closed before the comparator returns
+func (c Comparison) OpenRemote() (io.ReadCloser, error) {
+	return c.open()
+}
+
+// Comparator tells whether a local file and a remote file hold the same content
+type Comparator func(ctx context.Context, c Comparison) (bool, error)
+
+// CompareSize considers files with the same size as identical
+func CompareSize() Comparator {
+	return func(ctx context.Context, c Comparison) (bool, error) {
+		return uint64(c.Local.Size()) == c.Remote.Size, nil
+	}
+}
+
+// CompareSizeAndTime considers files with the same size and modification times distant of at most tolerance as
+// identical. The tolerance absorbs both clock skew and the low precision of listings
+func CompareSizeAndTime(tolerance time.Duration) Comparator {
+	return func(ctx context.Context, c Comparison) (bool, error) {
+		if uint64(c.Local.Size()) != c.Remote.Size {
+			return false, nil
+		}
+		d := c.Local.ModTime().Sub(c.Remote.Time)
+		if d < 0 {
+			d = -d
+		}
+		return d <= tolerance, nil
+	}
+}
+
+// CompareChecksum considers files with the same checksum as identical
+// The remote file has to be downloaded, which is only worth it when transfers are much slower than listings
+func CompareChecksum(h func() hash.Hash) Comparator {
+	return func(ctx context.Context, c Comparison) (same bool, err error) {
+		if uint64(c.Local.Size()) != c.Remote.Size {
+			return false, nil
+		}
+
+		// Local checksum
+		var lf *os.File
+		if lf, err = os.Open(c.LocalPath); err != nil {
+			return
+		}
+		defer lf.Close()
+		lh := h()
+		if _, err = copyContext(ctx, lh, lf, newTransferOptions(nil)); err != nil {
+			return
+		}
+
+		// Remote checksum
+		var r io.ReadCloser
+		if r, err = c.OpenRemote(); err != nil {
+			return
+		}
+		defer r.Close()
+		rh := h()
+		if _, err = copyContext(ctx, rh, r, newTransferOptions(nil)); err != nil {
+			return
+		}
+		return bytes.Equal(lh.Sum(nil), rh.Sum(nil)), nil
+	}
+}
+
+// same compares a local file and a remote file. Missing files are never the same
+func (f *FTP) same(ctx context.Context, conn ServerConnexion, local, remote string, cmp Comparator) (bool, error) {
+	// Local
+	info, err := os.Stat(local)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// Remote
+	e, err := f.stat(conn, remote)
+	if err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return cmp(ctx, Comparison{
+		Local:      info,
+		LocalPath:  local,
+		Remote:     e,
+		RemotePath: remote,
+		open:       func() (io.ReadCloser, error) { return f.retrFrom(conn, remote, 0) },
+	})
+}
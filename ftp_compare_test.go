@@ -0,0 +1,71 @@
+package ftp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_Upload_SkipIfSame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-ftp-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "ab-test.json")
+	if err = ioutil.WriteFile(src, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		cmp      ftp.Comparator
+		modTime  time.Time
+		wantStor bool
+	}{
+		{
+			name:     "Same size",
+			cmp:      ftp.CompareSize(),
+			modTime:  time.Now().Add(-time.Hour),
+			wantStor: false,
+		},
+		{
+			name:     "Same size and time within tolerance",
+			cmp:      ftp.CompareSizeAndTime(time.Minute),
+			modTime:  time.Now().Add(-30 * time.Second),
+			wantStor: false,
+		},
+		{
+			name:     "Same size but time out of tolerance",
+			cmp:      ftp.CompareSizeAndTime(time.Minute),
+			modTime:  time.Now().Add(-time.Hour),
+			wantStor: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chtimes(src, tt.modTime, tt.modTime); err != nil {
+				t.Fatal(err)
+			}
+			oConnexion := getMockOfServerConnexion(getListOfFiles()).(*mocks.ServerConnexion)
+			oConnexion.On("Stor", "folder/ab-test.json", mock.Anything).Return(nil)
+			oFtp := NewFtp(oConnexion)
+
+			if err := oFtp.Upload(context.Background(), src, "folder/ab-test.json", ftp.WithSkipIfSame(tt.cmp)); err != nil {
+				t.Fatalf("base.Upload() error = %v", err)
+			}
+			if tt.wantStor {
+				oConnexion.AssertCalled(t, "Stor", "folder/ab-test.json", mock.Anything)
+			} else {
+				oConnexion.AssertNotCalled(t, "Stor", "folder/ab-test.json", mock.Anything)
+			}
+		})
+	}
+}
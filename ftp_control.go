@@ -0,0 +1,134 @@
+package ftp
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// controlRecorder follows the control connection transcript in order to keep what the underlying client
+// doesn't expose, such as the FEAT reply
+// It is plugged as the client debug output so that it keeps seeing plain text once TLS is negotiated
+type controlRecorder struct {
+	banner   string
+	buf      []byte
+	cmd      string
+	features map[string]string
+	lines    []string
+	m        sync.Mutex
+}
+
+func newControlRecorder() *controlRecorder {
+	return &controlRecorder{}
+}
+
+// Write implements the io.Writer interface
+func (r *controlRecorder) Write(p []byte) (int, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.buf = append(r.buf, p...)
+	for {
+		i := bytes.IndexByte(r.buf, '\n')
+		if i < 0 {
+			break
+		}
+		r.line(strings.TrimRight(string(r.buf[:i]), "\r"))
+		r.buf = r.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// line processes a complete transcript line
+func (r *controlRecorder) line(l string) {
+	// Continuation of a multiline reply
+	if len(r.lines) > 0 {
+		r.lines = append(r.lines, l)
+		if len(l) >= 4 && l[:3] == r.lines[0][:3] && l[3] == ' ' {
+			r.reply(r.lines)
+			r.lines = nil
+		}
+		return
+	}
+
+	// Reply
+	if isReplyLine(l) {
+		if l[3] == '-' {
+			r.lines = []string{l}
+			return
+		}
+		r.reply([]string{l})
+		return
+	}
+
+	// Command, we only keep the verb so that credentials are never stored
+	r.cmd = strings.ToUpper(strings.SplitN(l, " ", 2)[0])
+}
+
+// reply processes a complete reply
+func (r *controlRecorder) reply(lines []string) {
+	switch r.cmd {
+	case "":
+		if r.banner == "" && lines[0][:3] == "220" {
+			r.banner = replyText(lines)
+		}
+	case "FEAT":
+		r.features = make(map[string]string)
+		// A single line reply has no features
+		if lines[0][:3] != "211" || len(lines) < 2 {
+			return
+		}
+		for _, l := range lines[1 : len(lines)-1] {
+			if !strings.HasPrefix(l, " ") {
+				continue
+			}
+			items := strings.SplitN(strings.TrimSpace(l), " ", 2)
+			var desc string
+			if len(items) == 2 {
+				desc = items[1]
+			}
+			r.features[strings.ToUpper(items[0])] = desc
+		}
+	}
+}
+
+// Features returns a copy of the features advertised in the FEAT reply
+func (r *controlRecorder) Features() map[string]string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	fs := make(map[string]string, len(r.features))
+	for k, v := range r.features {
+		fs[k] = v
+	}
+	return fs
+}
+
+// Banner returns the text of the greeting
+func (r *controlRecorder) Banner() string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.banner
+}
+
+// replyText returns the text of a reply, without its codes
+func replyText(lines []string) string {
+	ts := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if isReplyLine(l) && l[:3] == lines[0][:3] {
+			l = l[4:]
+		}
+		ts = append(ts, l)
+	}
+	return strings.Join(ts, "\n")
+}
+
+func isReplyLine(l string) bool {
+	if len(l) < 4 || (l[3] != ' ' && l[3] != '-') {
+		return false
+	}
+	for _, c := range l[:3] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package ftp
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestControlRecorder_Features(t *testing.T) {
+	r := newControlRecorder()
+	for _, chunk := range []string{
+		"220-Welcome\r\n220 FTP server ready\r\n",
+		"USER user\r\n",
+		"331 Password required\r\n",
+		"PASS secret\r\n",
+		"230 Logged in\r\n",
+		"FEAT\r\n",
+		"211-Features:\r\n MDTM\r\n REST STREAM\r\n SI",
+		"ZE\r\n UTF8\r\n211 End\r\n",
+		"TYPE I\r\n",
+		"200 Type set to I\r\n",
+	} {
+		r.Write([]byte(chunk))
+	}
+	want := map[string]string{"MDTM": "", "REST": "STREAM", "SIZE": "", "UTF8": ""}
+	if got := r.Features(); !reflect.DeepEqual(got, want) {
+		t.Errorf("controlRecorder.Features() = %v, want %v", got, want)
+	}
+}
+
+func TestControlRecorder_Banner(t *testing.T) {
+	r := newControlRecorder()
+	r.Write([]byte("220-Welcome\r\n220 vsFTPd 3.0.3\r\nUSER user\r\n331 Password required\r\n"))
+	r.Write([]byte("PASS secret\r\n230 Logged in\r\n"))
+	if want := "Welcome\nvsFTPd 3.0.3"; r.Banner() != want {
+		t.Errorf("controlRecorder.Banner() = %q, want %q", r.Banner(), want)
+	}
+}
+
+func TestControlRecorder_NoFeatures(t *testing.T) {
+	r := newControlRecorder()
+	r.Write([]byte("220 Ready\r\nFEAT\r\n211 No features\r\n"))
+	if got := r.Features(); len(got) != 0 {
+		t.Errorf("controlRecorder.Features() = %v, want none", got)
+	}
+}
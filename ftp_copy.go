@@ -0,0 +1,177 @@
+package ftp
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Pools reduce allocations when there are many transfers. Only buffers of the default size are pooled since other
+// sizes are rare
+var (
+	bufferPool = sync.Pool{New: func() interface{} {
+		b := make([]byte, defaultBufferSize)
+		return &b
+	}}
+	transferReaderPool = sync.Pool{New: func() interface{} { return &transferReader{} }}
+)
+
+func getBuffer(size int) *[]byte {
+	if size != defaultBufferSize {
+		b := make([]byte, size)
+		return &b
+	}
+	return bufferPool.Get().(*[]byte)
+}
+
+func putBuffer(b *[]byte) {
+	if len(*b) == defaultBufferSize {
+		bufferPool.Put(b)
+	}
+}
+
+// transferReader wraps the reader of a transfer in order to honor the context between chunks, limit the rate,
+// enforce the maximum size and report the progress
+type transferReader struct {
+	ctx   context.Context
+	n     int64
+	o     transferOptions
+	r     io.Reader
+	start time.Time
+}
+
+// newTransferReader returns a transfer reader from the pool, which should be given back with putTransferReader once
+// the transfer is done
+func newTransferReader(ctx context.Context, r io.Reader, o transferOptions) *transferReader {
+	tr := transferReaderPool.Get().(*transferReader)
+	*tr = transferReader{
+		ctx:   ctx,
+		o:     o,
+		r:     r,
+		start: time.Now(),
+	}
+	return tr
+}
+
+func putTransferReader(r *transferReader) {
+	*r = transferReader{}
+	transferReaderPool.Put(r)
+}
+
+// Read implements the io.Reader interface
+func (r *transferReader) Read(p []byte) (n int, err error) {
+	// Check context error
+	if err = r.ctx.Err(); err != nil {
+		return
+	}
+
+	// Don't read more than a second worth of bytes at once when rate is limited
+	if r.o.rateLimit > 0 && int64(len(p)) > r.o.rateLimit {
+		p = p[:r.o.rateLimit]
+	}
+
+	// Read
+	n, err = r.r.Read(p)
+	r.n += int64(n)
+	if r.o.maxSize > 0 && r.n > r.o.maxSize {
+		return n, ErrFileTooLarge
+	}
+	if n > 0 && r.o.progress != nil {
+		r.o.progress(r.n)
+	}
+
+	// Limit rate
+	if r.o.rateLimit > 0 && n > 0 {
+		if d := time.Duration(float64(r.n)/float64(r.o.rateLimit)*float64(time.Second)) - time.Since(r.start); d > 0 {
+			select {
+			case <-time.After(d):
+			case <-r.ctx.Done():
+				if err == nil {
+					err = r.ctx.Err()
+				}
+			}
+		}
+	}
+	return
+}
+
+// WriteTo implements the io.WriterTo interface, which io.Copy prefers, so that transfers copied by the client go
+// through a pooled buffer of the transfer buffer size as well
+func (r *transferReader) WriteTo(w io.Writer) (n int64, err error) {
+	b := getBuffer(r.o.bufferSize)
+	defer putBuffer(b)
+	for {
+		nr, errRead := r.Read(*b)
+		if nr > 0 {
+			nw, errWrite := w.Write((*b)[:nr])
+			n += int64(nw)
+			if errWrite != nil {
+				return n, errWrite
+			}
+			if nw != nr {
+				return n, io.ErrShortWrite
+			}
+		}
+		if errRead == io.EOF {
+			return n, nil
+		} else if errRead != nil {
+			return n, errRead
+		}
+	}
+}
+
+// copyContext copies src to dst with the transfer options, checking the context between chunks
+// It returns the number of bytes read from src, which differs from the number of bytes written when there are
+// transforms or encryption
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader, o transferOptions) (n int64, err error) {
+	r := newTransferReader(ctx, src, o)
+	defer putTransferReader(r)
+	if len(o.transforms) == 0 && o.encryption == nil && o.sniff == nil {
+		return r.WriteTo(dst)
+	}
+
+	// Decrypt, transform and sniff
+	var t io.ReadCloser
+	if t, err = decodeReader(r, o); err != nil {
+		return
+	}
+	defer t.Close()
+
+	// Copy
+	b := getBuffer(o.bufferSize)
+	defer putBuffer(b)
+	_, err = io.CopyBuffer(struct{ io.Writer }{dst}, t, *b)
+
+	// Transforms have to be done with r before its count is read
+	t.Close()
+	return r.n, err
+}
+
+// decodeReader returns a reader of the content of a download as the options want it: decrypted first, then
+// transformed, and sniffed last. Closing it closes the transforms
+func decodeReader(r io.Reader, o transferOptions) (io.ReadCloser, error) {
+	ts := o.transforms
+	if o.encryption != nil {
+		t, err := o.encryption.decryptTransform()
+		if err != nil {
+			return nil, err
+		}
+		ts = append([]transform{t}, ts...)
+	}
+	tr := applyTransforms(r, ts)
+
+	// Sniff what's read
+	if o.sniff != nil {
+		s, err := sniff(tr, o.sniff)
+		if err != nil {
+			tr.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{s, tr}, nil
+	}
+	return tr, nil
+}
@@ -0,0 +1,142 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyContext(t *testing.T) {
+	// Progress
+	var progress []int64
+	src := bytes.Repeat([]byte("a"), 10)
+	var dst bytes.Buffer
+	n, err := copyContext(context.Background(), &dst, bytes.NewReader(src), newTransferOptions([]TransferOption{
+		WithBufferSize(4),
+		WithProgress(func(n int64) { progress = append(progress, n) }),
+	}))
+	if err != nil || n != 10 || !bytes.Equal(dst.Bytes(), src) {
+		t.Fatalf("copyContext() = %d, %v, want 10, nil", n, err)
+	}
+	if want := []int64{4, 8, 10}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+
+	// Copies made by the client go through the transfer buffer as well
+	progress = nil
+	r := newTransferReader(context.Background(), bytes.NewReader(src), newTransferOptions([]TransferOption{
+		WithBufferSize(4),
+		WithProgress(func(n int64) { progress = append(progress, n) }),
+	}))
+	if _, err = io.Copy(&dst, r); err != nil {
+		t.Fatalf("io.Copy() error = %v", err)
+	}
+	putTransferReader(r)
+	if want := []int64{4, 8, 10}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("progress = %v, want %v", progress, want)
+	}
+
+	// Rate limit
+	now := time.Now()
+	if _, err = copyContext(context.Background(), &dst, bytes.NewReader(src), newTransferOptions([]TransferOption{
+		WithRateLimit(50),
+	})); err != nil {
+		t.Fatalf("copyContext() error = %v", err)
+	}
+	if d := time.Since(now); d < 150*time.Millisecond {
+		t.Errorf("rate limited copy took %s, want about 200ms", d)
+	}
+
+	// Cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err = copyContext(ctx, &dst, bytes.NewReader(src), newTransferOptions(nil)); err != context.Canceled {
+		t.Errorf("copyContext() error = %v, want %v", err, context.Canceled)
+	}
+
+	// Max size
+	o := newTransferOptions(nil)
+	o.maxSize = 5
+	if _, err = copyContext(context.Background(), &dst, bytes.NewReader(src), o); err != ErrFileTooLarge {
+		t.Errorf("copyContext() error = %v, want %v", err, ErrFileTooLarge)
+	}
+
+	// Transforms
+	dst.Reset()
+	o = newTransferOptions([]TransferOption{
+		WithReadTransform(func(r io.Reader) io.Reader { return io.MultiReader(r, strings.NewReader("b")) }),
+		WithWriteTransform(func(w io.Writer) io.WriteCloser { return nopWriteCloser{hexWriter{w}} }),
+	})
+	if n, err = copyContext(context.Background(), &dst, bytes.NewReader([]byte("a")), o); err != nil || n != 1 {
+		t.Fatalf("copyContext() = %d, %v, want 1, nil", n, err)
+	}
+	if dst.String() != "6162" {
+		t.Errorf("transformed content = %q, want %q", dst.String(), "6162")
+	}
+}
+
+func TestCopyContext_Sniff(t *testing.T) {
+	for _, v := range []struct {
+		content string
+		want    string
+	}{
+		{content: "<!DOCTYPE html><html><body>Not found</body></html>", want: "text/html; charset=utf-8"},
+		{content: "\x00\x00\x00\x18ftypmp42" + strings.Repeat("\x00", 1000), want: "video/mp4"},
+		{content: "", want: "text/plain; charset=utf-8"},
+	} {
+		var res TransferResult
+		var dst bytes.Buffer
+		n, err := copyContext(context.Background(), &dst, strings.NewReader(v.content), newTransferOptions([]TransferOption{WithContentSniffing(&res)}))
+		if err != nil || n != int64(len(v.content)) {
+			t.Fatalf("copyContext() = %d, %v, want %d, nil", n, err, len(v.content))
+		}
+		if dst.String() != v.content {
+			t.Errorf("copied content = %q, want %q", dst.String(), v.content)
+		}
+		if res.ContentType != v.want {
+			t.Errorf("content type = %q, want %q", res.ContentType, v.want)
+		}
+	}
+}
+
+type hexWriter struct{ w io.Writer }
+
+func (w hexWriter) Write(p []byte) (int, error) {
+	if _, err := w.w.Write([]byte(hex.EncodeToString(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// failingWriter fails once n bytes have been written
+type failingWriter struct{ n int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n -= len(p); w.n < 0 {
+		return 0, errors.New("disk full")
+	}
+	return len(p), nil
+}
+
+func TestCopyContext_TransformDstError(t *testing.T) {
+	src := bytes.Repeat([]byte("a"), 1<<20)
+	for i := 0; i < 100; i++ {
+		o := newTransferOptions([]TransferOption{
+			WithBufferSize(1024),
+			WithWriteTransform(func(w io.Writer) io.WriteCloser { return nopWriteCloser{w} }),
+		})
+		if _, err := copyContext(context.Background(), &failingWriter{n: 4096}, bytes.NewReader(src), o); err == nil || err.Error() != "disk full" {
+			t.Fatalf("copyContext() error = %v, want disk full", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ftp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// sidecarSuffix is the suffix of the sidecar holding the checksum of a remote file, in the sha256sum format
+const sidecarSuffix = ".sha256"
+
+// sidecar returns the content of the sidecar of a file
+func sidecar(sum, name string) string {
+	return fmt.Sprintf("%s  %s\n", sum, path.Base(name))
+}
+
+// localSum computes the checksum and the size of a local file, and rewinds it
+func localSum(ctx context.Context, src *os.File) (sum string, size int64, err error) {
+	h := sha256.New()
+	if size, err = copyContext(ctx, h, src, newTransferOptions(nil)); err != nil {
+		return
+	}
+	if _, err = src.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// sameSum checks whether a remote file has a given size and a sidecar holding a given checksum
+func (f *FTP) sameSum(conn ServerConnexion, dst, sum string, size int64) (same bool, err error) {
+	// Remote size
+	e, err := f.stat(conn, dst)
+	if err == ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return
+	} else if int64(e.Size) != size {
+		return
+	}
+
+	// Remote checksum
+	var remote string
+	if remote, err = f.readSidecar(conn, dst+sidecarSuffix); err != nil {
+		if isFileUnavailable(err) {
+			err = nil
+		}
+		return
+	}
+	return remote == sum, nil
+}
+
+// readSidecar returns the checksum of a sidecar
+func (f *FTP) readSidecar(conn ServerConnexion, p string) (sum string, err error) {
+	var r io.ReadCloser
+	if r, err = f.retrFrom(conn, p, 0); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	var b []byte
+	if b, err = ioutil.ReadAll(io.LimitReader(r, 1024)); err != nil {
+		return
+	}
+	if fields := strings.Fields(string(b)); len(fields) > 0 {
+		sum = strings.ToLower(fields[0])
+	}
+	return
+}
@@ -1,6 +1,9 @@
 package ftp
 
 import (
+	"crypto/tls"
+	"net"
+	"syscall"
 	"time"
 
 	"github.com/jlaffaye/ftp"
@@ -11,13 +14,150 @@ type Dialer interface {
 	DialTimeout(addr string, timeout time.Duration) (conn ServerConnexion, err error)
 }
 
-type defaultDialer struct{}
+// TLS modes
+const (
+	TLSExplicit = "explicit"
+	TLSImplicit = "implicit"
+)
+
+// defaultDialer dials with the client of github.com/jlaffaye/ftp
+// Control connections to hosts with both IPv4 and IPv6 addresses are dialed Happy Eyeballs style (RFC 6555): the
+// addresses of the other family are tried in parallel if the first one hasn't connected after the fallback delay,
+// 300ms by default, so that a broken family doesn't cost a whole timeout. A negative delay disables the fallback
+type defaultDialer struct {
+	control         func(network, address string, c syscall.RawConn) error
+	dataConnTimeout time.Duration
+	disableEPSV     bool
+	disableMLSD     bool
+	disableUTF8     bool
+	fallbackDelay   time.Duration
+	resolver        *net.Resolver
+	sessionCache    tls.ClientSessionCache
+	tls             string
+	tlsConfig       *tls.Config
+	transferTimeout time.Duration
+}
 
 func (d *defaultDialer) Dial(addr string) (conn ServerConnexion, err error) {
-	return ftp.Dial(addr)
+	return d.dial(addr, 0)
 }
 func (d *defaultDialer) DialTimeout(addr string, timeout time.Duration) (conn ServerConnexion, err error) {
-	return ftp.DialTimeout(addr, timeout)
+	return d.dial(addr, timeout)
+}
+
+func (d *defaultDialer) dial(addr string, timeout time.Duration) (ServerConnexion, error) {
+	c := &serverConnexion{r: newControlRecorder()}
+	options := []ftp.DialOption{
+		ftp.DialWithDialFunc(d.dialFunc(c, timeout)),
+		ftp.DialWithDebugOutput(c.r),
+		ftp.DialWithDisabledEPSV(d.disableEPSV),
+		ftp.DialWithDisabledMLSD(d.disableMLSD),
+		ftp.DialWithDisabledUTF8(d.disableUTF8),
+	}
+	switch d.tls {
+	case TLSExplicit:
+		options = append(options, ftp.DialWithExplicitTLS(d.clientTLSConfig(addr)))
+	case TLSImplicit:
+		// The dial function wraps the connections, but the client still has to know about TLS to send PBSZ and PROT
+		options = append(options, ftp.DialWithTLS(d.clientTLSConfig(addr)))
+	}
+	var err error
+	if c.ServerConn, err = ftp.Dial(addr, options...); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// clientTLSConfig returns the TLS configuration, with the server name set to the host of addr if it's missing, and
+// the session cache of the dialer when sessions are reused so that data connections resume the control one
+func (d *defaultDialer) clientTLSConfig(addr string) *tls.Config {
+	c := &tls.Config{}
+	if d.tlsConfig != nil {
+		c = d.tlsConfig.Clone()
+	}
+	if c.ClientSessionCache == nil {
+		c.ClientSessionCache = d.sessionCache
+	}
+	if c.ServerName == "" {
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			c.ServerName = host
+		}
+	}
+	return c
+}
+
+// dialFunc returns the function the client dials with: the first connection is the control connection and the
+// following ones are data connections, which get their own timeout and a deadline for the whole transfer
+// The client doesn't handle TLS itself when it's given a dial function, so connections are wrapped here: the control
+// connection only in implicit mode since the client upgrades it after AUTH TLS in explicit mode, and data
+// connections in both modes
+func (d *defaultDialer) dialFunc(c *serverConnexion, timeout time.Duration) func(network, address string) (net.Conn, error) {
+	var tlsConfig *tls.Config
+	return func(network, address string) (net.Conn, error) {
+		// Control connection
+		if c.conn == nil {
+			conn, err := (&net.Dialer{
+				Control:       d.control,
+				FallbackDelay: d.fallbackDelay,
+				Resolver:      d.resolver,
+				Timeout:       timeout,
+			}).Dial(network, address)
+			if err != nil {
+				return nil, err
+			}
+			c.conn = conn
+			if d.tls != "" {
+				tlsConfig = d.clientTLSConfig(address)
+			}
+			switch d.tls {
+			case TLSExplicit:
+				return conn, nil
+			case TLSImplicit:
+				c.control = tls.Client(conn, tlsConfig)
+			default:
+				c.control = conn
+			}
+			return c.control, nil
+		}
+
+		// Data connection
+		dataConnTimeout := d.dataConnTimeout
+		if dataConnTimeout == 0 {
+			dataConnTimeout = timeout
+		}
+		conn, err := (&net.Dialer{Timeout: dataConnTimeout}).Dial(network, address)
+		if err != nil {
+			return nil, err
+		}
+		if d.transferTimeout > 0 {
+			if err = conn.SetDeadline(time.Now().Add(d.transferTimeout)); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		if tlsConfig != nil {
+			return tls.Client(conn, tlsConfig), nil
+		}
+		return conn, nil
+	}
+}
+
+// configure returns a copy of the dialer using the timeouts and switches of a configuration
+func (d *defaultDialer) configure(c Configuration) *defaultDialer {
+	n := &defaultDialer{
+		dataConnTimeout: c.DataConnTimeout,
+		disableEPSV:     c.DisableEPSV,
+		disableMLSD:     c.DisableMLSD,
+		disableUTF8:     c.DisableUTF8,
+		fallbackDelay:   c.DualStackFallbackDelay,
+		tls:             c.TLS,
+		tlsConfig:       c.TLSConfig,
+		transferTimeout: c.TransferTimeout,
+	}
+	if c.TLSSessionReuse {
+		n.sessionCache = tls.NewLRUClientSessionCache(0)
+	}
+	return n
 }
 
 // Comment
@@ -0,0 +1,306 @@
+package ftp
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDefaultDialer_DialFunc(t *testing.T) {
+	l := newTestListener(t)
+	defer l.Close()
+
+	d := (&defaultDialer{}).configure(Configuration{TransferTimeout: 50 * time.Millisecond})
+	c := &serverConnexion{}
+	dial := d.dialFunc(c, time.Second)
+
+	// Control connection has no deadline
+	control, err := dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Close()
+	if c.conn != control {
+		t.Fatal("control connection was not stored")
+	}
+
+	// Data connection times out after the transfer timeout
+	data, err := dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Close()
+	if _, err = data.Read(make([]byte, 1)); err == nil {
+		t.Fatal("expected an error")
+	} else if e, ok := err.(net.Error); !ok || !e.Timeout() {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
+
+func TestDefaultDialer_DialFunc_TLS(t *testing.T) {
+	l := newTestListener(t)
+	defer l.Close()
+
+	for _, mode := range []string{TLSExplicit, TLSImplicit} {
+		d := (&defaultDialer{}).configure(Configuration{TLS: mode})
+		dial := d.dialFunc(&serverConnexion{}, time.Second)
+
+		// The client upgrades the control connection itself in explicit mode
+		control, err := dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer control.Close()
+		if _, ok := control.(*tls.Conn); ok != (mode == TLSImplicit) {
+			t.Errorf("%s: control connection is TLS = %v", mode, ok)
+		}
+
+		// Data connections
+		data, err := dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer data.Close()
+		if _, ok := data.(*tls.Conn); !ok {
+			t.Errorf("%s: data connection is not TLS", mode)
+		}
+	}
+}
+
+func TestDefaultDialer_DialFunc_DualStack(t *testing.T) {
+	l := newTestListener(t)
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	// The host resolves to ::1, which is tried first but doesn't accept connections, and to the IPv4 address of the
+	// listener. The IPv6 attempt either fails right away or only after a while
+	newDialer := func(delay, slow time.Duration) *defaultDialer {
+		d := (&defaultDialer{}).configure(Configuration{DualStackFallbackDelay: delay})
+		d.resolver = newTestResolver(net.ParseIP("::1"), net.ParseIP("127.0.0.1"))
+		d.control = func(network, address string, c syscall.RawConn) error {
+			if host, _, _ := net.SplitHostPort(address); net.ParseIP(host).To4() == nil {
+				time.Sleep(slow)
+			}
+			return nil
+		}
+		return d
+	}
+	for _, v := range []struct {
+		delay time.Duration
+		max   time.Duration
+		min   time.Duration
+		name  string
+		slow  time.Duration
+	}{
+		{delay: 10 * time.Millisecond, max: 400 * time.Millisecond, name: "slow primary", slow: 500 * time.Millisecond},
+		{delay: time.Hour, max: 400 * time.Millisecond, name: "failing primary"},
+		{delay: -1, min: 500 * time.Millisecond, name: "no fallback", slow: 500 * time.Millisecond},
+	} {
+		now := time.Now()
+		conn, err := newDialer(v.delay, v.slow).dialFunc(&serverConnexion{}, 5*time.Second)("tcp", net.JoinHostPort("ftp.example.com", port))
+		if err != nil {
+			t.Fatalf("%s: dial error = %v", v.name, err)
+		}
+		conn.Close()
+		if ip := conn.RemoteAddr().(*net.TCPAddr).IP; ip.To4() == nil {
+			t.Errorf("%s: connected to %s, want an IPv4 address", v.name, ip)
+		}
+		if e := time.Since(now); e < v.min || (v.max > 0 && e > v.max) {
+			t.Errorf("%s: dial took %s", v.name, e)
+		}
+	}
+}
+
+// newTestResolver returns a resolver answering A and AAAA queries with ips whatever the host
+func newTestResolver(ips ...net.IP) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go func() {
+				defer server.Close()
+				for {
+					// Queries are prefixed with their length over stream connections
+					var n uint16
+					if err := binary.Read(server, binary.BigEndian, &n); err != nil {
+						return
+					}
+					q := make([]byte, n)
+					if _, err := io.ReadFull(server, q); err != nil {
+						return
+					}
+
+					// Header, question and answers pointing to the question name
+					end := 12
+					for q[end] != 0 {
+						end += int(q[end]) + 1
+					}
+					qtype := binary.BigEndian.Uint16(q[end+1:])
+					var answers [][]byte
+					for _, ip := range ips {
+						rdata := []byte(ip.To4())
+						if qtype == 28 {
+							if ip.To4() != nil {
+								continue
+							}
+							rdata = []byte(ip.To16())
+						} else if rdata == nil {
+							continue
+						}
+						a := []byte{0xc0, 12, 0, byte(qtype), 0, 1, 0, 0, 0, 60, 0, byte(len(rdata))}
+						answers = append(answers, append(a, rdata...))
+					}
+					r := append([]byte{q[0], q[1], 0x85, 0x80, 0, 1, 0, byte(len(answers)), 0, 0, 0, 0}, q[12:end+5]...)
+					for _, a := range answers {
+						r = append(r, a...)
+					}
+					if err := binary.Write(server, binary.BigEndian, uint16(len(r))); err != nil {
+						return
+					}
+					if _, err := server.Write(r); err != nil {
+						return
+					}
+				}
+			}()
+			return client, nil
+		},
+	}
+}
+
+func TestDefaultDialer_Dial_TLS(t *testing.T) {
+	for _, mode := range []string{TLSExplicit, TLSImplicit} {
+		s := newTestTLSServer(t, mode == TLSImplicit)
+		d := (&defaultDialer{}).configure(Configuration{TLS: mode, TLSConfig: &tls.Config{InsecureSkipVerify: true}})
+		conn, err := d.DialTimeout(s.l.Addr().String(), time.Second)
+		if err != nil {
+			t.Fatalf("%s: dial error = %v", mode, err)
+		}
+		if err = conn.Login("user", "password"); err != nil {
+			t.Fatalf("%s: login error = %v", mode, err)
+		}
+		conn.Quit()
+		s.l.Close()
+
+		// Data connections are only protected once the server is told so
+		cmds := strings.Join(s.commands(), ",")
+		if !strings.Contains(cmds, "PBSZ 0,PROT P") {
+			t.Errorf("%s: commands = %s, want PBSZ 0 and PROT P", mode, cmds)
+		}
+	}
+}
+
+// testTLSServer is an FTP server over TLS answering commands with success and recording them
+type testTLSServer struct {
+	cmds []string
+	l    net.Listener
+	m    sync.Mutex
+}
+
+func newTestTLSServer(t *testing.T, implicit bool) *testTLSServer {
+	// Certificate
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		NotAfter:     time.Now().Add(time.Hour),
+		SerialNumber: big.NewInt(1),
+	}, &x509.Certificate{NotAfter: time.Now().Add(time.Hour), SerialNumber: big.NewInt(1)}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+
+	// Serve
+	s := &testTLSServer{l: newTestTCPListener(t)}
+	go func() {
+		for {
+			conn, err := s.l.Accept()
+			if err != nil {
+				return
+			}
+			if implicit {
+				conn = tls.Server(conn, config)
+			}
+			go s.serve(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testTLSServer) serve(conn net.Conn, config *tls.Config) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	conn.Write([]byte("220 Ready\r\n"))
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(l)
+		s.m.Lock()
+		s.cmds = append(s.cmds, cmd)
+		s.m.Unlock()
+		switch {
+		case cmd == "AUTH TLS":
+			conn.Write([]byte("234 Proceed\r\n"))
+			conn = tls.Server(conn, config)
+			r = bufio.NewReader(conn)
+		case cmd == "FEAT":
+			conn.Write([]byte("211 End\r\n"))
+		case strings.HasPrefix(cmd, "USER"):
+			conn.Write([]byte("331 Password required\r\n"))
+		case strings.HasPrefix(cmd, "PASS"):
+			conn.Write([]byte("230 Logged in\r\n"))
+		case cmd == "QUIT":
+			conn.Write([]byte("221 Bye\r\n"))
+			return
+		default:
+			conn.Write([]byte("200 OK\r\n"))
+		}
+	}
+}
+
+func (s *testTLSServer) commands() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string(nil), s.cmds...)
+}
+
+func newTestTCPListener(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func newTestListener(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	return l
+}
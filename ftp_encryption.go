@@ -0,0 +1,226 @@
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Encrypted streams start with a header made of a magic string, the chunk size and a random nonce prefix, followed by
+// chunks of at most chunk size bytes of plaintext, each sealed with AES-GCM
+// The nonce of a chunk is made of the prefix, the index of the chunk and a flag set on the last chunk only, so that
+// reordered, dropped or truncated chunks fail authentication. The header is authenticated with every chunk
+const (
+	encryptionChunkSize   = 64 << 10
+	encryptionMagic       = "MFTPAES1"
+	encryptionMaxChunk    = 16 << 20
+	encryptionPrefixSize  = 7
+	encryptionHeaderSize  = len(encryptionMagic) + 4 + encryptionPrefixSize
+	encryptionMaxCounters = math.MaxUint32
+)
+
+type encryption struct {
+	key func() ([]byte, error)
+}
+
+// WithEncryption encrypts uploads and decrypts downloads with AES-GCM and a 16, 24 or 32 bytes key
+// Content is encrypted last in uploads, after compression and transforms, and decrypted first in downloads.
+// Encrypted uploads can't be retried since the stream can't be rewound
+func WithEncryption(key []byte) TransferOption {
+	return WithEncryptionKeyProvider(func() ([]byte, error) { return key, nil })
+}
+
+// WithEncryptionKeyProvider is like WithEncryption, except that the key is asked to fn at the start of the transfer
+func WithEncryptionKeyProvider(fn func() ([]byte, error)) TransferOption {
+	return func(o *transferOptions) {
+		o.encryption = &encryption{key: fn}
+	}
+}
+
+func (e *encryption) aead() (cipher.AEAD, error) {
+	key, err := e.key()
+	if err != nil {
+		return nil, err
+	}
+	b, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
+
+// encryptReader returns a reader of the encrypted content of r
+func (e *encryption) encryptReader(r io.Reader) (io.ReadCloser, error) {
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, encryptionHeaderSize)
+	copy(header, encryptionMagic)
+	binary.BigEndian.PutUint32(header[len(encryptionMagic):], encryptionChunkSize)
+	if _, err = rand.Read(header[len(encryptionMagic)+4:]); err != nil {
+		return nil, err
+	}
+	return pipeWriter(r, func(w io.Writer) io.WriteCloser {
+		return &encryptWriter{aead: aead, buf: make([]byte, 0, encryptionChunkSize), header: header, w: w}
+	}), nil
+}
+
+// decryptTransform returns the transform decrypting a stream
+func (e *encryption) decryptTransform() (transform, error) {
+	aead, err := e.aead()
+	if err != nil {
+		return nil, err
+	}
+	return func(r io.Reader) io.ReadCloser {
+		return &decryptReader{aead: aead, r: bufio.NewReader(r)}
+	}, nil
+}
+
+// nonce returns the nonce of a chunk
+func nonce(header []byte, counter uint32, last bool) []byte {
+	n := make([]byte, 12)
+	copy(n, header[len(header)-encryptionPrefixSize:])
+	binary.BigEndian.PutUint32(n[encryptionPrefixSize:], counter)
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+type encryptWriter struct {
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint32
+	header  []byte
+	started bool
+	w       io.Writer
+}
+
+// Write implements the io.Writer interface
+// A full chunk is only sealed once more data comes in, since the last chunk is only known when the writer is closed
+func (w *encryptWriter) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		if len(w.buf) == cap(w.buf) {
+			if err = w.seal(false); err != nil {
+				return
+			}
+		}
+		i := cap(w.buf) - len(w.buf)
+		if i > len(p) {
+			i = len(p)
+		}
+		w.buf = append(w.buf, p[:i]...)
+		p = p[i:]
+		n += i
+	}
+	return
+}
+
+// Close seals the last chunk
+func (w *encryptWriter) Close() error {
+	return w.seal(true)
+}
+
+func (w *encryptWriter) seal(last bool) (err error) {
+	if !w.started {
+		if _, err = w.w.Write(w.header); err != nil {
+			return
+		}
+		w.started = true
+	}
+	if w.counter == encryptionMaxCounters {
+		return errors.New("ftp: too many chunks to encrypt")
+	}
+	if _, err = w.w.Write(w.aead.Seal(nil, nonce(w.header, w.counter, last), w.buf, w.header)); err != nil {
+		return
+	}
+	w.buf = w.buf[:0]
+	w.counter++
+	return
+}
+
+type decryptReader struct {
+	aead    cipher.AEAD
+	chunk   []byte
+	counter uint32
+	done    bool
+	header  []byte
+	plain   []byte
+	r       *bufio.Reader
+}
+
+// Read implements the io.Reader interface
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// Close implements the io.Closer interface
+func (r *decryptReader) Close() error {
+	return nil
+}
+
+// next decrypts the next chunk
+func (r *decryptReader) next() error {
+	// Header
+	if r.header == nil {
+		header := make([]byte, encryptionHeaderSize)
+		if _, err := io.ReadFull(r.r, header); err != nil {
+			return ErrDecryption
+		}
+		if !bytes.Equal(header[:len(encryptionMagic)], []byte(encryptionMagic)) {
+			return ErrDecryption
+		}
+		size := binary.BigEndian.Uint32(header[len(encryptionMagic):])
+		if size == 0 || size > encryptionMaxChunk {
+			return ErrDecryption
+		}
+		r.header = header
+		r.chunk = make([]byte, int(size)+r.aead.Overhead())
+	}
+
+	// Read the chunk, a chunk is the last one when it's not full or when nothing follows it
+	n, err := io.ReadFull(r.r, r.chunk)
+	var last bool
+	switch err {
+	case nil:
+		if _, err = r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		return ErrDecryption
+	default:
+		return err
+	}
+
+	// Decrypt
+	if r.counter == encryptionMaxCounters {
+		return ErrDecryption
+	}
+	if r.plain, err = r.aead.Open(r.plain[:0], nonce(r.header, r.counter, last), r.chunk[:n], r.header); err != nil {
+		return ErrDecryption
+	}
+	r.counter++
+	r.done = last
+	return nil
+}
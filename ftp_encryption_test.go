@@ -0,0 +1,57 @@
+package ftp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	e := &encryption{key: func() ([]byte, error) { return bytes.Repeat([]byte("k"), 32), nil }}
+	for _, size := range []int{0, 1, encryptionChunkSize, 2*encryptionChunkSize + 10} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		r, err := e.encryptReader(bytes.NewReader(plain))
+		if err != nil {
+			t.Fatalf("encryptReader() error = %v", err)
+		}
+		enc, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("encrypting %d bytes error = %v", size, err)
+		}
+		if size >= 16 && bytes.Contains(enc, plain) {
+			t.Errorf("encrypted content of %d bytes contains the plaintext", size)
+		}
+
+		d, _ := e.decryptTransform()
+		got, err := ioutil.ReadAll(d(bytes.NewReader(enc)))
+		if err != nil {
+			t.Fatalf("decrypting %d bytes error = %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("decrypting %d bytes = %d bytes, want the plaintext", size, len(got))
+		}
+
+		// Dropping the last chunk is detected
+		if size > encryptionChunkSize {
+			truncated := enc[:encryptionHeaderSize+encryptionChunkSize+16]
+			if _, err = ioutil.ReadAll(d(bytes.NewReader(truncated))); err != ErrDecryption {
+				t.Errorf("decrypting a truncated stream error = %v, want %v", err, ErrDecryption)
+			}
+		}
+	}
+}
+
+func TestEncryption_WrongKey(t *testing.T) {
+	r, _ := (&encryption{key: func() ([]byte, error) { return bytes.Repeat([]byte("a"), 16), nil }}).encryptReader(strings.NewReader("content"))
+	enc, _ := ioutil.ReadAll(r)
+	d, _ := (&encryption{key: func() ([]byte, error) { return bytes.Repeat([]byte("b"), 16), nil }}).decryptTransform()
+	if _, err := ioutil.ReadAll(d(bytes.NewReader(enc))); err != ErrDecryption {
+		t.Errorf("decrypting with the wrong key error = %v, want %v", err, ErrDecryption)
+	}
+	if _, err := (&encryption{key: func() ([]byte, error) { return []byte("short"), nil }}).decryptTransform(); err == nil {
+		t.Error("decryptTransform() with an invalid key error = nil")
+	}
+}
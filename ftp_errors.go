@@ -0,0 +1,49 @@
+package ftp
+
+import (
+	"errors"
+	"net/textproto"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// Errors
+var (
+	ErrChecksumMismatch   = errors.New("ftp: checksum mismatch")
+	ErrClaimed            = errors.New("ftp: already claimed")
+	ErrDecryption         = errors.New("ftp: decryption failed")
+	ErrFileTooLarge       = errors.New("ftp: file too large")
+	ErrIncompleteTransfer = errors.New("ftp: incomplete transfer")
+	ErrInvalidPath        = errors.New("ftp: invalid path")
+	ErrLockLost           = errors.New("ftp: lock lost")
+	ErrLocked             = errors.New("ftp: locked")
+	ErrNoTrash            = errors.New("ftp: no trash folder")
+	ErrNotFound           = errors.New("ftp: file not found")
+	ErrUnexpectedBanner   = errors.New("ftp: unexpected banner")
+	ErrUnsupported        = errors.New("ftp: unsupported")
+)
+
+// isNotImplemented checks whether an error is the server rejecting a command it doesn't support
+func isNotImplemented(err error) bool {
+	e, ok := err.(*textproto.Error)
+	if !ok {
+		return false
+	}
+	switch e.Code {
+	case ftp.StatusBadCommand, ftp.StatusBadArguments, ftp.StatusNotImplemented, ftp.StatusNotImplementedParameter:
+		return true
+	}
+	return false
+}
+
+// isLoginFailed checks whether an error is the server rejecting credentials
+func isLoginFailed(err error) bool {
+	e, ok := err.(*textproto.Error)
+	return ok && e.Code == ftp.StatusNotLoggedIn
+}
+
+// isFileUnavailable checks whether an error is the server replying that a file doesn't exist or can't be accessed
+func isFileUnavailable(err error) bool {
+	e, ok := err.(*textproto.Error)
+	return ok && e.Code == ftp.StatusFileUnavailable
+}
@@ -0,0 +1,310 @@
+package ftp_test
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+// fakeServer is an in-memory FTP server for the tests that need data connections, which the mocks can't provide
+// since the client returns concrete responses
+type fakeServer struct {
+	cmds  []string
+	drops map[string][]int
+	files map[string][]byte
+	l     net.Listener
+	m     sync.Mutex
+	times map[string]time.Time
+}
+
+func newFakeServer(t *testing.T, files map[string]string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{drops: make(map[string][]int), files: make(map[string][]byte), l: l, times: make(map[string]time.Time)}
+	for p, c := range files {
+		s.files[p] = []byte(c)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// ftp returns a client of the server using the default dialer
+func (s *fakeServer) ftp(c ftp.Configuration) *ftp.FTP {
+	c.Addr = s.l.Addr().String()
+	return ftp.New(c, ftp.NewDefaultDialer())
+}
+
+// drop makes the next downloads of a file stop after n bytes, the data connection being closed cleanly
+func (s *fakeServer) drop(p string, n ...int) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.drops[p] = append(s.drops[p], n...)
+}
+
+// put sets the content of a file
+func (s *fakeServer) put(p, c string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.files[p] = []byte(c)
+}
+
+// modTime sets the modification time of a file in listings, files having none being listed as of January 1st
+func (s *fakeServer) modTime(p string, t time.Time) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.times[p] = t
+}
+
+func (s *fakeServer) file(p string) (string, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	b, ok := s.files[p]
+	return string(b), ok
+}
+
+func (s *fakeServer) commands() []string {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]string(nil), s.cmds...)
+}
+
+// count returns how many commands start with prefix
+func (s *fakeServer) count(prefix string) (n int) {
+	for _, c := range s.commands() {
+		if strings.HasPrefix(c, prefix) {
+			n++
+		}
+	}
+	return
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) { fmt.Fprintf(conn, format+"\r\n", args...) }
+	reply("220 Fake server ready")
+
+	var data net.Listener
+	var rest int64
+	var from string
+	defer func() {
+		if data != nil {
+			data.Close()
+		}
+	}()
+	for {
+		l, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		l = strings.TrimRight(l, "\r\n")
+		s.m.Lock()
+		s.cmds = append(s.cmds, l)
+		s.m.Unlock()
+		items := strings.SplitN(l, " ", 2)
+		var arg string
+		if len(items) == 2 {
+			arg = items[1]
+		}
+
+		switch strings.ToUpper(items[0]) {
+		case "USER":
+			reply("331 Password required")
+		case "PASS":
+			reply("230 Logged in")
+		case "FEAT":
+			reply("211-Features:\r\n SIZE\r\n REST STREAM\r\n211 End")
+		case "TYPE", "NOOP":
+			reply("200 OK")
+		case "PWD":
+			reply(`257 "/" is the current directory`)
+		case "CWD":
+			if s.isDir(arg) {
+				reply("250 OK")
+			} else {
+				reply("550 No such directory")
+			}
+		case "MKD":
+			reply(`257 "%s" created`, arg)
+		case "EPSV":
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 Can't open data connection")
+				continue
+			}
+			reply("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "REST":
+			rest, _ = strconv.ParseInt(arg, 10, 64)
+			reply("350 Restarting at %d", rest)
+		case "SIZE":
+			if c, ok := s.file(arg); ok {
+				reply("213 %d", len(c))
+			} else {
+				reply("550 No such file")
+			}
+		case "RETR":
+			c, ok := s.file(arg)
+			if !ok {
+				reply("550 No such file")
+				continue
+			}
+			if rest > int64(len(c)) {
+				rest = int64(len(c))
+			}
+			c = c[rest:]
+			s.m.Lock()
+			if ds := s.drops[arg]; len(ds) > 0 {
+				if ds[0] < len(c) {
+					c = c[:ds[0]]
+				}
+				s.drops[arg] = ds[1:]
+			}
+			s.m.Unlock()
+			s.transfer(data, reply, func(dc net.Conn) { dc.Write([]byte(c)) })
+			data, rest = nil, 0
+		case "STOR", "APPE":
+			p, appe, offset := arg, strings.EqualFold(items[0], "APPE"), rest
+			s.transfer(data, reply, func(dc net.Conn) {
+				b, _ := ioutil.ReadAll(dc)
+				s.m.Lock()
+				defer s.m.Unlock()
+				s.times[p] = time.Now()
+				switch {
+				case appe:
+					s.files[p] = append(s.files[p], b...)
+				case offset > 0 && offset <= int64(len(s.files[p])):
+					s.files[p] = append(s.files[p][:offset], b...)
+				default:
+					s.files[p] = b
+				}
+			})
+			data, rest = nil, 0
+		case "LIST":
+			if !s.isDir(arg) {
+				reply("550 No such directory")
+				continue
+			}
+			lines := s.list(arg)
+			s.transfer(data, reply, func(dc net.Conn) {
+				for _, l := range lines {
+					fmt.Fprintf(dc, "%s\r\n", l)
+				}
+			})
+			data = nil
+		case "DELE":
+			s.m.Lock()
+			_, ok := s.files[arg]
+			delete(s.files, arg)
+			delete(s.times, arg)
+			s.m.Unlock()
+			if ok {
+				reply("250 Deleted")
+			} else {
+				reply("550 No such file")
+			}
+		case "RNFR":
+			from = arg
+			reply("350 Ready for destination")
+		case "RNTO":
+			s.m.Lock()
+			c, ok := s.files[from]
+			if ok {
+				delete(s.files, from)
+				s.files[arg] = c
+				s.times[arg] = s.times[from]
+				delete(s.times, from)
+			}
+			s.m.Unlock()
+			if ok {
+				reply("250 Renamed")
+			} else {
+				reply("550 No such file")
+			}
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+// transfer runs fn on the data connection the client opened after EPSV
+func (s *fakeServer) transfer(data net.Listener, reply func(string, ...interface{}), fn func(dc net.Conn)) {
+	if data == nil {
+		reply("425 Use EPSV first")
+		return
+	}
+	defer data.Close()
+	dc, err := data.Accept()
+	if err != nil {
+		reply("425 Can't open data connection")
+		return
+	}
+	reply("150 Opening data connection")
+	fn(dc)
+	dc.Close()
+	reply("226 Transfer complete")
+}
+
+// isDir checks whether a folder has files
+func (s *fakeServer) isDir(dir string) bool {
+	dir = path.Clean("/" + dir)
+	s.m.Lock()
+	defer s.m.Unlock()
+	for p := range s.files {
+		if strings.HasPrefix(path.Clean("/"+p), strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return dir == "/"
+}
+
+// list returns the LIST lines of a folder
+func (s *fakeServer) list(dir string) (lines []string) {
+	dir = path.Clean("/" + dir)
+	s.m.Lock()
+	defer s.m.Unlock()
+	dirs := make(map[string]bool)
+	for p, c := range s.files {
+		t := "Jan 01 00:00"
+		if mt, ok := s.times[p]; ok {
+			t = mt.UTC().Format("Jan _2 15:04")
+		}
+		p = path.Clean("/" + p)
+		rel := strings.TrimPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+		if rel == p {
+			continue
+		}
+		if i := strings.Index(rel, "/"); i >= 0 {
+			dirs[rel[:i]] = true
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("-rw-r--r-- 1 ftp ftp %d %s %s", len(c), t, rel))
+	}
+	for d := range dirs {
+		lines = append(lines, fmt.Sprintf("drwxr-xr-x 1 ftp ftp 0 Jan 01 00:00 %s", d))
+	}
+	sort.Strings(lines)
+	return
+}
@@ -0,0 +1,14 @@
+package ftp
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+const gzipSuffix = ".gz"
+
+// gzipReader returns a reader of the gzip compressed content of r, compressed in a goroutine as it's read
+// Closing the reader stops the compression
+func gzipReader(r io.Reader) io.ReadCloser {
+	return pipeWriter(r, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+}
@@ -0,0 +1,225 @@
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	log "github.com/molotovtv/go-logger"
+)
+
+// Heartbeat files are named after the original name of the leased file with the lease suffix, and kept in the lease
+// folder of the folder of the file so that consumers listing the files of the folder don't take them for work
+// A heartbeat file holds the claim suffix, so that the claimed file can be found back exactly
+const (
+	leaseDir    = ".leases"
+	leaseSuffix = ".lease"
+)
+
+// leasePath returns the path of the heartbeat file of a file
+func leasePath(p string) string {
+	return path.Join(path.Dir(p), leaseDir, path.Base(p)+leaseSuffix)
+}
+
+// Lease represents a file claimed for processing, kept alive by a heartbeat file touched periodically
+// A file whose heartbeat stopped for longer than the lease TTL, because its consumer crashed for instance, is given
+// back to the other consumers by ReclaimExpired
+type Lease struct {
+	Original    string
+	Path        string
+	cancel      context.CancelFunc
+	claimSuffix string
+	done        chan struct{}
+	f           *FTP
+	lost        chan struct{}
+	o           sync.Once
+}
+
+// AcquireLease claims a file with Claim and starts a heartbeat touching its heartbeat file every third of ttl
+// The lease must be ended with Release once the file is processed, or Return to give it back to the other consumers
+func (f *FTP) AcquireLease(ctx context.Context, p, claimSuffix string, ttl time.Duration) (l *Lease, err error) {
+	if ttl < time.Second {
+		return nil, fmt.Errorf("ftp: invalid lease ttl %s", ttl)
+	}
+
+	// Claim
+	var claimed string
+	if claimed, err = f.Claim(ctx, p, claimSuffix); err != nil {
+		return
+	}
+
+	// Write the heartbeat file, creating the lease folder if needed
+	if err = f.writeLease(p, claimSuffix, true); err != nil {
+		if errRename := f.Rename(claimed, p); errRename != nil {
+			log.Errorf("[FTP] error while giving %s back : %s", claimed, errRename.Error())
+		}
+		return
+	}
+
+	// Heartbeat
+	hctx, cancel := context.WithCancel(context.Background())
+	l = &Lease{
+		Original:    p,
+		Path:        claimed,
+		cancel:      cancel,
+		claimSuffix: claimSuffix,
+		done:        make(chan struct{}),
+		f:           f,
+		lost:        make(chan struct{}),
+	}
+	go l.heartbeat(hctx, ttl)
+	return
+}
+
+func (l *Lease) heartbeat(ctx context.Context, ttl time.Duration) {
+	defer close(l.done)
+	t := time.NewTicker(ttl / 3)
+	defer t.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := l.f.writeLease(l.Original, l.claimSuffix, false); err != nil {
+				log.Errorf("[FTP] error while renewing lease of %s : %s", l.Path, err.Error())
+				if time.Since(last) > ttl {
+					l.o.Do(func() { close(l.lost) })
+				}
+				continue
+			}
+			last = time.Now()
+		}
+	}
+}
+
+// writeLease writes the heartbeat file of a file claimed with a suffix, after creating the lease folder if asked to
+func (f *FTP) writeLease(p, claimSuffix string, create bool) (err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	lp := leasePath(p)
+	if create {
+		f.checkFolders(conn, path.Dir(lp))
+	}
+	return f.createFile(conn, lp, strings.NewReader(claimSuffix))
+}
+
+// readLease returns the claim suffix held by a heartbeat file
+func (f *FTP) readLease(conn ServerConnexion, p string) (claimSuffix string, err error) {
+	var r io.ReadCloser
+	if r, err = f.retrFrom(conn, p, 0); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	var b []byte
+	if b, err = ioutil.ReadAll(io.LimitReader(r, 1024)); err != nil {
+		return
+	}
+	return string(b), nil
+}
+
+// Lost is closed when the heartbeat has failed for longer than the TTL, in which case the file may have been given
+// to another consumer
+func (l *Lease) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// stop stops the heartbeat and waits for it to return
+func (l *Lease) stop() {
+	l.cancel()
+	<-l.done
+}
+
+// Release ends the lease once the file has been processed, leaving the claimed file for the caller to deal with
+func (l *Lease) Release() error {
+	l.stop()
+	return l.f.delete(leasePath(l.Original))
+}
+
+// Return ends the lease and gives the file back to the other consumers
+func (l *Lease) Return() error {
+	l.stop()
+	if err := l.f.Rename(l.Path, l.Original); err != nil {
+		return err
+	}
+	return l.f.delete(leasePath(l.Original))
+}
+
+// ReclaimExpired gives the files of a folder whose lease expired back to the consumers, and returns their paths
+// Expiry is based on the listing times, so ttl must absorb the clock skew with the server and the low precision of
+// listings
+func (f *FTP) ReclaimExpired(ctx context.Context, folder string, ttl time.Duration) (reclaimed []string, err error) {
+	// Log
+	l := fmt.Sprintf("FTP reclaim of expired leases in %s", folder)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// List the heartbeat files and the files
+	dir := path.Join(folder, leaseDir)
+	var leases, entries map[string]*ftp.Entry
+	if leases, err = f.listFiles(conn, dir); err != nil {
+		return
+	}
+	if len(leases) == 0 {
+		return
+	}
+	if entries, err = f.listFiles(conn, folder); err != nil {
+		return
+	}
+
+	for name, e := range leases {
+		if !strings.HasSuffix(name, leaseSuffix) || time.Since(e.Time) < ttl {
+			continue
+		}
+
+		// Read the claim suffix, heartbeat files without one can't be matched with their claimed file and are left alone
+		var claimSuffix string
+		if claimSuffix, err = f.readLease(conn, path.Join(dir, name)); err != nil {
+			return
+		}
+		if claimSuffix == "" {
+			log.Warnf("[FTP] lease %s has no claim suffix, skipping it", path.Join(dir, name))
+			continue
+		}
+
+		// Give the claimed file back
+		original := strings.TrimSuffix(name, leaseSuffix)
+		if _, ok := entries[original+claimSuffix]; ok {
+			if err = conn.Rename(path.Join(folder, original+claimSuffix), path.Join(folder, original)); err != nil {
+				return
+			}
+			log.Warnf("[FTP] lease of %s expired, giving it back", path.Join(folder, original+claimSuffix))
+			reclaimed = append(reclaimed, path.Join(folder, original))
+		}
+
+		// Remove the heartbeat file
+		if err = conn.Delete(path.Join(dir, name)); err != nil {
+			return
+		}
+	}
+	return
+}
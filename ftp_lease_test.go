@@ -0,0 +1,93 @@
+package ftp_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_Lease(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Rename", "/inbox/a.xml", "/inbox/a.xml.worker-1").Return(nil)
+	oConnexion.On("FileSize", "/inbox/.leases").Return(int64(0), nil)
+	oConnexion.On("Stor", "/inbox/.leases/a.xml.lease", mock.Anything).Return(nil)
+	oConnexion.On("Delete", "/inbox/.leases/a.xml.lease").Return(nil)
+	oFtp := NewFtp(oConnexion)
+
+	l, err := oFtp.AcquireLease(context.Background(), "/inbox/a.xml", ".worker-1", time.Second)
+	if err != nil {
+		t.Fatalf("base.AcquireLease() error = %v", err)
+	}
+	if l.Path != "/inbox/a.xml.worker-1" {
+		t.Errorf("lease path = %s, want /inbox/a.xml.worker-1", l.Path)
+	}
+
+	// Heartbeat
+	time.Sleep(400 * time.Millisecond)
+	if err = l.Release(); err != nil {
+		t.Fatalf("Lease.Release() error = %v", err)
+	}
+	var stors int
+	for _, c := range oConnexion.Calls {
+		if c.Method == "Stor" {
+			stors++
+			if b, _ := ioutil.ReadAll(c.Arguments.Get(1).(io.Reader)); string(b) != ".worker-1" {
+				t.Errorf("heartbeat file = %q, want the claim suffix", b)
+			}
+		}
+	}
+	if stors < 2 {
+		t.Errorf("heartbeat file written %d times, want at least 2", stors)
+	}
+	oConnexion.AssertCalled(t, "Delete", "/inbox/.leases/a.xml.lease")
+}
+
+func TestFTP_ReclaimExpired(t *testing.T) {
+	s := newFakeServer(t, map[string]string{
+		"/inbox/a.xml.worker-1":      "a",
+		"/inbox/.leases/a.xml.lease": ".worker-1",
+		"/inbox/a.xml.worker-1.bak":  "a",
+		"/inbox/b.xml.worker-2":      "b",
+		"/inbox/.leases/b.xml.lease": ".worker-2",
+		"/inbox/c.xml":               "c",
+		"/inbox/.leases/d.xml.lease": "",
+		"/inbox/d.xml.worker-3":      "d",
+		"/inbox/.leases/e.xml.lease": ".worker-4",
+		"/inbox/e.xml.worker-4.done": "e",
+	})
+	s.modTime("/inbox/.leases/b.xml.lease", time.Now())
+	oFtp := s.ftp(ftp.Configuration{})
+
+	reclaimed, err := oFtp.ReclaimExpired(context.Background(), "/inbox", time.Minute)
+	if err != nil {
+		t.Fatalf("base.ReclaimExpired() error = %v", err)
+	}
+	if want := []string{"/inbox/a.xml"}; !reflect.DeepEqual(reclaimed, want) {
+		t.Errorf("base.ReclaimExpired() = %v, want %v", reclaimed, want)
+	}
+
+	// Only the file claimed with the suffix of the lease is given back
+	for p, want := range map[string]bool{
+		"/inbox/a.xml":               true,
+		"/inbox/a.xml.worker-1":      false,
+		"/inbox/a.xml.worker-1.bak":  true,
+		"/inbox/.leases/a.xml.lease": false,
+		"/inbox/b.xml.worker-2":      true,
+		"/inbox/.leases/b.xml.lease": true,
+		"/inbox/.leases/d.xml.lease": true,
+		"/inbox/d.xml.worker-3":      true,
+		"/inbox/.leases/e.xml.lease": false,
+		"/inbox/e.xml.worker-4.done": true,
+	} {
+		if _, ok := s.file(p); ok != want {
+			t.Errorf("%s exists = %v, want %v", p, ok, want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// newLockToken returns a token identifying the owner of a lock
+func newLockToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b)), nil
+}
+
+// WithLock runs fn while holding a lock file, following the convention of drop folders where a lock file tells the
+// other side not to touch the folder
+// It fails with ErrLocked if the lock file already exists, unless it's older than the LockStaleAfter duration in
+// which case it's considered abandoned and replaced. FTP has no exclusive create, so the lock file is read back after
+// being written to make sure no one else took it in between, and again once fn has returned. This doesn't make the lock
+// exclusive: clients writing it at the same time may both run fn, the one whose lock was overwritten getting
+// ErrLockLost afterwards, in which case the lock file is left to its new owner
+func (f *FTP) WithLock(ctx context.Context, lockPath string, fn func() error) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP lock of %s", lockPath)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Lock
+	var token string
+	if token, err = f.lock(ctx, lockPath); err != nil {
+		return
+	}
+
+	// Unlock
+	defer func() {
+		if errUnlock := f.unlock(lockPath, token); err == nil {
+			err = errUnlock
+		}
+	}()
+	return fn()
+}
+
+func (f *FTP) lock(ctx context.Context, p string) (token string, err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Existing lock
+	e, err := f.stat(conn, p)
+	if err != nil && err != ErrNotFound {
+		return
+	} else if err == nil {
+		created := e.Time
+		if _, t, errRead := f.readLock(conn, p); errRead == nil && !t.IsZero() {
+			created = t
+		}
+		if f.LockStaleAfter <= 0 || time.Since(created) < f.LockStaleAfter {
+			return "", ErrLocked
+		}
+		log.Warnf("[FTP] removing stale lock %s created at %s", p, created)
+		if err = conn.Delete(p); err != nil {
+			return
+		}
+	}
+
+	// Check context error
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	// Write lock
+	if token, err = newLockToken(); err != nil {
+		return
+	}
+	if err = f.writeLock(conn, p, token); err != nil {
+		return
+	}
+
+	// Make sure the lock is ours, and don't leave it behind if that can't be known
+	var owner string
+	if owner, _, err = f.readLock(conn, p); err != nil {
+		if errDelete := conn.Delete(p); errDelete != nil {
+			log.Errorf("[FTP] error while removing lock %s : %s", p, errDelete.Error())
+		}
+		return "", err
+	} else if owner != token {
+		return "", ErrLocked
+	}
+	return
+}
+
+// unlock removes a lock file, unless it's no longer ours
+func (f *FTP) unlock(p, token string) (err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Make sure the lock is still ours
+	var owner string
+	if owner, _, err = f.readLock(conn, p); err != nil {
+		if isFileUnavailable(err) {
+			err = ErrLockLost
+		}
+		return
+	} else if owner != token {
+		return ErrLockLost
+	}
+
+	// Remove
+	log.Debugf("Removing %s", p)
+	return conn.Delete(p)
+}
+
+func (f *FTP) writeLock(conn ServerConnexion, p, token string) (err error) {
+	var restore func() error
+	if p, restore, err = f.transferPath(conn, p); err != nil {
+		return
+	}
+	defer restoreDir(restore, &err)
+	return conn.Stor(p, bytes.NewReader([]byte(token+" "+time.Now().UTC().Format(time.RFC3339)+"\n")))
+}
+
+// readLock returns the owner token and the creation time of a lock file
+func (f *FTP) readLock(conn ServerConnexion, p string) (token string, created time.Time, err error) {
+	var r io.ReadCloser
+	if r, err = f.retrFrom(conn, p, 0); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	var b []byte
+	if b, err = ioutil.ReadAll(io.LimitReader(r, 1024)); err != nil {
+		return
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) > 0 {
+		token = fields[0]
+	}
+	if len(fields) > 1 {
+		created, _ = time.Parse(time.RFC3339, fields[1])
+	}
+	return
+}
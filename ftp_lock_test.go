@@ -0,0 +1,96 @@
+package ftp_test
+
+import (
+	"context"
+	"net/textproto"
+	"testing"
+	"time"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_WithLock_Locked(t *testing.T) {
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/drop").Return([]*base.Entry{{Name: ".lock", Time: time.Now(), Type: base.EntryTypeFile}}, nil)
+	oConnexion.On("Retr", "/drop/.lock").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such file"})
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{LockStaleAfter: time.Hour}, oDialer)
+
+	var called bool
+	if err := oFtp.WithLock(context.Background(), "/drop/.lock", func() error {
+		called = true
+		return nil
+	}); err != ftp.ErrLocked {
+		t.Fatalf("base.WithLock() error = %v, want %v", err, ftp.ErrLocked)
+	}
+	if called {
+		t.Error("callback should not run when the lock is held")
+	}
+	oConnexion.AssertNotCalled(t, "Delete", mock.Anything)
+}
+
+func TestFTP_WithLock(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/drop/a.xml": "a"})
+	oFtp := s.ftp(ftp.Configuration{TrashDir: "/trash"})
+
+	var held bool
+	if err := oFtp.WithLock(context.Background(), "/drop/.lock", func() error {
+		_, held = s.file("/drop/.lock")
+		return nil
+	}); err != nil {
+		t.Fatalf("base.WithLock() error = %v", err)
+	}
+	if !held {
+		t.Error("lock file should exist while the callback runs")
+	}
+
+	// The lock file is deleted, not trashed
+	if _, ok := s.file("/drop/.lock"); ok {
+		t.Error("lock file should be removed")
+	}
+	if n := s.count("RNFR"); n != 0 {
+		t.Errorf("%d files moved, want the lock file to bypass the trash", n)
+	}
+}
+
+func TestFTP_WithLock_Lost(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/drop/a.xml": "a"})
+	oFtp := s.ftp(ftp.Configuration{})
+
+	// Someone else overwrites the lock while the callback runs
+	if err := oFtp.WithLock(context.Background(), "/drop/.lock", func() error {
+		s.put("/drop/.lock", "other-owner")
+		return nil
+	}); err != ftp.ErrLockLost {
+		t.Fatalf("base.WithLock() error = %v, want %v", err, ftp.ErrLockLost)
+	}
+	if c, _ := s.file("/drop/.lock"); c != "other-owner" {
+		t.Errorf("lock file = %q, want it left to its new owner", c)
+	}
+}
+
+func TestFTP_WithLock_ReadBackError(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "/drop/.lock", mock.Anything).Return(nil)
+	oConnexion.On("Retr", "/drop/.lock").Return(nil, &textproto.Error{Code: base.StatusTransfertAborted, Msg: "Transfer aborted"})
+	oConnexion.On("Delete", "/drop/.lock").Return(nil)
+
+	var called bool
+	if err := NewFtp(oConnexion).WithLock(context.Background(), "/drop/.lock", func() error {
+		called = true
+		return nil
+	}); err == nil {
+		t.Fatal("base.WithLock() should fail")
+	}
+	if called {
+		t.Error("callback should not run when the lock can't be read back")
+	}
+	oConnexion.AssertCalled(t, "Delete", "/drop/.lock")
+}
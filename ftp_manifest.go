@@ -0,0 +1,184 @@
+package ftp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	log "github.com/molotovtv/go-logger"
+)
+
+// Manifest represents the files of a tree
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+// ManifestFile represents a file of a manifest, its path being relative to the root of the tree and slash
+// separated
+type ManifestFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// LocalManifest generates the manifest of a local tree, with the checksums of the files if withChecksums is true
+func LocalManifest(ctx context.Context, root string, withChecksums bool) (m Manifest, err error) {
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		// Check context error
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+
+		var rel string
+		if rel, err = filepath.Rel(root, p); err != nil {
+			return err
+		}
+		mf := ManifestFile{Path: filepath.ToSlash(rel), Size: fi.Size()}
+		if withChecksums {
+			if mf.SHA256, err = localChecksum(ctx, p); err != nil {
+				return err
+			}
+		}
+		m.Files = append(m.Files, mf)
+		return nil
+	})
+	return
+}
+
+func localChecksum(ctx context.Context, p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err = copyContext(ctx, h, f, newTransferOptions(nil)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyReport represents the result of the verification of a remote tree against a manifest
+type VerifyReport struct {
+	Checked          int      `json:"checked"`
+	ChecksumMismatch []string `json:"checksum_mismatch,omitempty"`
+	Missing          []string `json:"missing,omitempty"`
+	SizeMismatch     []string `json:"size_mismatch,omitempty"`
+}
+
+// OK checks whether the remote tree matches the manifest
+func (r VerifyReport) OK() bool {
+	return len(r.ChecksumMismatch) == 0 && len(r.Missing) == 0 && len(r.SizeMismatch) == 0
+}
+
+// VerifyManifest checks that the files of a manifest exist under a remote root with the same size, and the same
+// checksum when the manifest has one. Remote files are downloaded to compute checksums, so manifests without
+// checksums are much cheaper to verify
+// Each folder is listed only once, and mismatches are reported rather than returned as errors
+func (f *FTP) VerifyManifest(ctx context.Context, root string, m Manifest) (r VerifyReport, err error) {
+	// Log
+	l := fmt.Sprintf("FTP verify of %d files in %s", len(m.Files), root)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Group files by folder
+	folders := make(map[string][]ManifestFile)
+	for _, mf := range m.Files {
+		p := path.Join(root, mf.Path)
+		folders[path.Dir(p)] = append(folders[path.Dir(p)], mf)
+	}
+	var names []string
+	for dir := range folders {
+		names = append(names, dir)
+	}
+	sort.Strings(names)
+
+	for _, dir := range names {
+		// Check context error
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// List
+		var entries map[string]*ftp.Entry
+		if entries, err = f.listFiles(conn, dir); err != nil {
+			return
+		}
+
+		for _, mf := range folders[dir] {
+			r.Checked++
+			e, ok := entries[path.Base(mf.Path)]
+			if !ok {
+				r.Missing = append(r.Missing, mf.Path)
+				continue
+			}
+			if int64(e.Size) != mf.Size {
+				r.SizeMismatch = append(r.SizeMismatch, mf.Path)
+				continue
+			}
+			if mf.SHA256 == "" {
+				continue
+			}
+
+			// Checksum
+			var sum string
+			if sum, err = f.remoteChecksum(ctx, conn, path.Join(root, mf.Path)); err != nil {
+				return
+			}
+			if sum != mf.SHA256 {
+				r.ChecksumMismatch = append(r.ChecksumMismatch, mf.Path)
+			}
+		}
+	}
+	return
+}
+
+// listFiles returns the files of a remote folder indexed by name, a folder that doesn't exist having no files
+func (f *FTP) listFiles(conn ServerConnexion, dir string) (map[string]*ftp.Entry, error) {
+	entries, err := conn.List(dir)
+	if err != nil {
+		if isFileUnavailable(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	files := make(map[string]*ftp.Entry, len(entries))
+	for _, e := range entries {
+		if e.Type == ftp.EntryTypeFile {
+			files[e.Name] = e
+		}
+	}
+	return files, nil
+}
+
+func (f *FTP) remoteChecksum(ctx context.Context, conn ServerConnexion, p string) (string, error) {
+	r, err := f.retrFrom(conn, p, 0)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err = copyContext(ctx, h, r, newTransferOptions(nil)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
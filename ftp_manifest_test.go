@@ -0,0 +1,90 @@
+package ftp_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+)
+
+func TestFTP_VerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "a"), 0755)
+	os.MkdirAll(filepath.Join(dir, "b"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "a", "ok.mp4"), []byte("0123"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "truncated.mp4"), []byte("0123"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a", "missing.mp4"), []byte("0123"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b", "missing.mp4"), []byte("0123"), 0644)
+	m, err := ftp.LocalManifest(context.Background(), dir, false)
+	if err != nil {
+		t.Fatalf("ftp.LocalManifest() error = %v", err)
+	}
+	if len(m.Files) != 4 {
+		t.Fatalf("ftp.LocalManifest() has %d files, want 4", len(m.Files))
+	}
+
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/publish/a").Return([]*base.Entry{
+		{Name: "ok.mp4", Size: 4, Type: base.EntryTypeFile},
+		{Name: "truncated.mp4", Size: 2, Type: base.EntryTypeFile},
+	}, nil)
+	oConnexion.On("List", "/publish/b").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such directory"})
+	oFtp := NewFtp(oConnexion)
+
+	r, err := oFtp.VerifyManifest(context.Background(), "/publish", m)
+	if err != nil {
+		t.Fatalf("base.VerifyManifest() error = %v", err)
+	}
+	if want := (ftp.VerifyReport{
+		Checked:      4,
+		Missing:      []string{"a/missing.mp4", "b/missing.mp4"},
+		SizeMismatch: []string{"a/truncated.mp4"},
+	}); !reflect.DeepEqual(r, want) {
+		t.Errorf("base.VerifyManifest() = %+v, want %+v", r, want)
+	}
+	if r.OK() {
+		t.Error("report should not be OK")
+	}
+}
+
+func TestFTP_VerifyManifest_Checksum(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "ok.mp4"), []byte("0123"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "corrupted.mp4"), []byte("0123"), 0644)
+	m, err := ftp.LocalManifest(context.Background(), dir, true)
+	if err != nil {
+		t.Fatalf("ftp.LocalManifest() error = %v", err)
+	}
+
+	// Same size, different content
+	s := newFakeServer(t, map[string]string{"/publish/ok.mp4": "0123", "/publish/corrupted.mp4": "0124"})
+	oFtp := s.ftp(ftp.Configuration{})
+	defer oFtp.Close()
+
+	r, err := oFtp.VerifyManifest(context.Background(), "/publish", m)
+	if err != nil {
+		t.Fatalf("base.VerifyManifest() error = %v", err)
+	}
+	if want := (ftp.VerifyReport{
+		Checked:          2,
+		ChecksumMismatch: []string{"corrupted.mp4"},
+	}); !reflect.DeepEqual(r, want) {
+		t.Errorf("base.VerifyManifest() = %+v, want %+v", r, want)
+	}
+	if r.OK() {
+		t.Error("report should not be OK")
+	}
+	if n := s.count("RETR"); n != 2 {
+		t.Errorf("%d downloads, want 2", n)
+	}
+}
@@ -0,0 +1,115 @@
+package ftp
+
+// defaultBufferSize is the buffer size used by transfers when none is provided
+const defaultBufferSize = 1 << 20
+
+// TransferOption represents a transfer option
+type TransferOption func(o *transferOptions)
+
+type transferOptions struct {
+	allocate   bool
+	bufferSize int
+	comparator Comparator
+	encryption *encryption
+	gzip       bool
+	hashDedup  bool
+	maxSize    int64
+	progress   func(n int64)
+	rateLimit  int64
+	retry      *RetryPolicy
+	sniff      *TransferResult
+	spill      bool
+	transforms []transform
+}
+
+func newTransferOptions(opts []TransferOption) transferOptions {
+	o := transferOptions{bufferSize: defaultBufferSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// encoded checks whether the content is compressed, transformed or encrypted on its way, in which case what's sent
+// differs from the source
+func (o transferOptions) encoded() bool {
+	return o.gzip || len(o.transforms) > 0 || o.encryption != nil
+}
+
+// WithBufferSize sets the size of the buffer used during the transfer
+func WithBufferSize(n int) TransferOption {
+	return func(o *transferOptions) {
+		if n > 0 {
+			o.bufferSize = n
+		}
+	}
+}
+
+// WithProgress calls fn with the number of bytes transferred so far every time a chunk is transferred
+func WithProgress(fn func(n int64)) TransferOption {
+	return func(o *transferOptions) {
+		o.progress = fn
+	}
+}
+
+// WithRateLimit limits the transfer to bytesPerSecond
+func WithRateLimit(bytesPerSecond int64) TransferOption {
+	return func(o *transferOptions) {
+		o.rateLimit = bytesPerSecond
+	}
+}
+
+// WithSpillToFile makes streamed uploads be written to a temporary file first, and only sent once complete,
+// for servers requiring the size to be known when the transfer starts
+func WithSpillToFile() TransferOption {
+	return func(o *transferOptions) {
+		o.spill = true
+	}
+}
+
+// WithSkipIfSame skips the transfer when the comparator considers the source and the destination identical
+func WithSkipIfSame(c Comparator) TransferOption {
+	return func(o *transferOptions) {
+		o.comparator = c
+	}
+}
+
+// WithGzip gzip compresses uploads on the fly, and appends the .gz suffix to the destination unless it already has it
+// Compressed uploads can't be retried since the stream can't be rewound
+func WithGzip() TransferOption {
+	return func(o *transferOptions) {
+		o.gzip = true
+	}
+}
+
+// WithHashDedup skips uploads when the remote file has the same size and the same SHA-256 checksum according to its
+// sidecar, named after the file with the .sha256 suffix, and writes the sidecar after each upload
+// The remote file has to be the source as is, so uploads combining it with WithGzip, WithReadTransform,
+// WithWriteTransform or WithEncryption fail with ErrUnsupported
+func WithHashDedup() TransferOption {
+	return func(o *transferOptions) {
+		o.hashDedup = true
+	}
+}
+
+// WithAllocate sends ALLO with the size of the upload before sending it when the size is known, which is the case of
+// files and in-memory readers, for servers that have to reserve space beforehand
+// It's skipped with a warning when the control connection is upgraded to TLS with AUTH TLS, and when the server doesn't
+// implement it
+func WithAllocate() TransferOption {
+	return func(o *transferOptions) {
+		o.allocate = true
+	}
+}
+
+// WithRetryPolicy overrides the retry policy of the instance for this call
+func WithRetryPolicy(p RetryPolicy) TransferOption {
+	return func(o *transferOptions) {
+		o.retry = &p
+	}
+}
+
+// WithNoRetry disables retries for this call, so that it fails fast
+func WithNoRetry() TransferOption {
+	return WithRetryPolicy(RetryPolicy{})
+}
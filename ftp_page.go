@@ -0,0 +1,149 @@
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	log "github.com/molotovtv/go-logger"
+)
+
+// Sort fields
+const (
+	SortByName = "name"
+	SortBySize = "size"
+	SortByTime = "time"
+)
+
+// SortSpec represents the order of a listing
+// Entries with the same value are ordered by name so that the order is stable from one page to the other
+type SortSpec struct {
+	By   string
+	Desc bool
+}
+
+type listCacheEntry struct {
+	entries []*ftp.Entry
+	t       time.Time
+}
+
+// ListPage returns the entries of a folder between offset and offset+limit once sorted, as well as the total number
+// of entries
+// FTP can't list part of a folder, so listings are cached for ListCacheTTL to avoid listing gigantic folders again
+// for every page. The listing of a folder is dropped as soon as a file is uploaded to, removed from or renamed in it
+// through this instance
+func (f *FTP) ListPage(ctx context.Context, folder string, offset, limit int, spec SortSpec) (entries []*ftp.Entry, total int, err error) {
+	// Log
+	l := fmt.Sprintf("FTP list page of %s at %d", folder, offset)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Sort function
+	var less func(a, b *ftp.Entry) bool
+	switch spec.By {
+	case "", SortByName:
+		less = func(a, b *ftp.Entry) bool { return false }
+	case SortBySize:
+		less = func(a, b *ftp.Entry) bool { return a.Size < b.Size }
+	case SortByTime:
+		less = func(a, b *ftp.Entry) bool { return a.Time.Before(b.Time) }
+	default:
+		return nil, 0, fmt.Errorf("ftp: unknown sort field %s", spec.By)
+	}
+
+	// List
+	var all []*ftp.Entry
+	if all, err = f.cachedList(ctx, folder); err != nil {
+		return
+	}
+
+	// Sort a copy since the listing may be cached
+	sorted := make([]*ftp.Entry, len(all))
+	copy(sorted, all)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if spec.Desc {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		} else if less(b, a) {
+			return false
+		}
+		return a.Name < b.Name
+	})
+
+	// Window
+	total = len(sorted)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return sorted[offset:end], total, nil
+}
+
+// cachedList lists a folder, without . and .., or returns its cached listing if it's recent enough
+func (f *FTP) cachedList(ctx context.Context, folder string) (entries []*ftp.Entry, err error) {
+	// Cache
+	key := path.Clean(folder)
+	if f.ListCacheTTL > 0 {
+		f.lm.Lock()
+		c, ok := f.listCache[key]
+		f.lm.Unlock()
+		if ok && time.Since(c.t) < f.ListCacheTTL {
+			return c.entries, nil
+		}
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// List
+	var raw []*ftp.Entry
+	if raw, err = conn.List(folder); err != nil {
+		return
+	}
+	for _, e := range raw {
+		if e.Name != "." && e.Name != ".." {
+			entries = append(entries, e)
+		}
+	}
+
+	// Cache, evicting the listings that expired so that folders listed once don't stay in memory
+	if f.ListCacheTTL > 0 {
+		f.lm.Lock()
+		if f.listCache == nil {
+			f.listCache = make(map[string]listCacheEntry)
+		}
+		for k, c := range f.listCache {
+			if time.Since(c.t) >= f.ListCacheTTL {
+				delete(f.listCache, k)
+			}
+		}
+		f.listCache[key] = listCacheEntry{entries: entries, t: time.Now()}
+		f.lm.Unlock()
+	}
+	return
+}
+
+// invalidateList drops the cached listing of the folder of p, whose content has changed
+func (f *FTP) invalidateList(p string) {
+	f.lm.Lock()
+	delete(f.listCache, path.Dir(p))
+	f.lm.Unlock()
+}
@@ -0,0 +1,109 @@
+package ftp_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_ListPage(t *testing.T) {
+	now := time.Now()
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/catalog").Return([]*base.Entry{
+		{Name: ".", Type: base.EntryTypeFolder},
+		{Name: "d.xml", Size: 1, Time: now.Add(-time.Hour)},
+		{Name: "b.xml", Size: 3, Time: now},
+		{Name: "a.xml", Size: 2, Time: now.Add(-2 * time.Hour)},
+		{Name: "c.xml", Size: 3, Time: now.Add(-3 * time.Hour)},
+	}, nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{ListCacheTTL: time.Minute}, oDialer)
+
+	for _, tt := range []struct {
+		offset, limit int
+		spec          ftp.SortSpec
+		want          []string
+	}{
+		{0, 2, ftp.SortSpec{}, []string{"a.xml", "b.xml"}},
+		{2, 2, ftp.SortSpec{}, []string{"c.xml", "d.xml"}},
+		{4, 2, ftp.SortSpec{}, nil},
+		{0, 0, ftp.SortSpec{By: ftp.SortBySize}, []string{"d.xml", "a.xml", "b.xml", "c.xml"}},
+		{0, 2, ftp.SortSpec{By: ftp.SortBySize, Desc: true}, []string{"c.xml", "b.xml"}},
+		{1, 1, ftp.SortSpec{By: ftp.SortByTime, Desc: true}, []string{"d.xml"}},
+	} {
+		entries, total, err := oFtp.ListPage(context.Background(), "/catalog", tt.offset, tt.limit, tt.spec)
+		if err != nil {
+			t.Fatalf("base.ListPage() error = %v", err)
+		}
+		if total != 4 {
+			t.Errorf("total = %d, want 4", total)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name)
+		}
+		if len(names) != len(tt.want) {
+			t.Errorf("base.ListPage(%d, %d, %+v) = %v, want %v", tt.offset, tt.limit, tt.spec, names, tt.want)
+			continue
+		}
+		for i := range names {
+			if names[i] != tt.want[i] {
+				t.Errorf("base.ListPage(%d, %d, %+v) = %v, want %v", tt.offset, tt.limit, tt.spec, names, tt.want)
+				break
+			}
+		}
+	}
+
+	// Listing is cached
+	oConnexion.AssertNumberOfCalls(t, "List", 1)
+}
+
+func TestFTP_ListPage_Invalidation(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/catalog/a.xml": "a", "/other/z.xml": "z"})
+	oFtp := s.ftp(ftp.Configuration{ListCacheTTL: time.Minute})
+	defer oFtp.Close()
+
+	total := func() int {
+		_, n, err := oFtp.ListPage(context.Background(), "/catalog/", 0, 0, ftp.SortSpec{})
+		if err != nil {
+			t.Fatalf("base.ListPage() error = %v", err)
+		}
+		return n
+	}
+	if n := total(); n != 1 {
+		t.Fatalf("total = %d, want 1", n)
+	}
+	for _, step := range []struct {
+		name string
+		fn   func() error
+		want int
+	}{
+		{"upload", func() error { return oFtp.UploadReader(context.Background(), strings.NewReader("b"), "/catalog/b.xml") }, 2},
+		{"rename", func() error { return oFtp.Rename("/catalog/b.xml", "/other/b.xml") }, 1},
+		{"rename back", func() error { return oFtp.Rename("/other/b.xml", "/catalog/b.xml") }, 2},
+		{"remove", func() error { return oFtp.Remove("/catalog/b.xml") }, 1},
+	} {
+		if err := step.fn(); err != nil {
+			t.Fatalf("%s error = %v", step.name, err)
+		}
+		if n := total(); n != step.want {
+			t.Errorf("total after %s = %d, want %d", step.name, n, step.want)
+		}
+	}
+
+	// Listing is still cached when nothing changed
+	total()
+	if n := s.count("LIST"); n != 5 {
+		t.Errorf("%d listings, want 5", n)
+	}
+}
@@ -0,0 +1,214 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+	"sync"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// poolIdleCheck is the idle duration after which a pooled connection is checked with a NOOP before being reused
+const poolIdleCheck = 10 * time.Second
+
+// errDirty is given to release for connections whose state couldn't be restored, so that they're not reused
+var errDirty = errors.New("ftp: connection state can't be restored")
+
+// pool keeps logged in connections around so that operations don't have to dial and log in every time
+type pool struct {
+	idle     []pooledConn
+	m        sync.Mutex
+	min      int
+	o        sync.Once
+	ready    chan struct{}
+	readyErr error
+	size     int
+	warm     bool
+}
+
+type pooledConn struct {
+	conn ServerConnexion
+	t    time.Time
+}
+
+func newPool(size, min int, warm bool) *pool {
+	if min > size {
+		min = size
+	}
+	return &pool{
+		min:   min,
+		ready: make(chan struct{}),
+		size:  size,
+		warm:  warm,
+	}
+}
+
+// warmUp establishes the minimum number of connections in parallel, only once and only if the pool is meant to be
+// warmed up, each connection taking an operation slot while it's established
+func (p *pool) warmUp(f *FTP) {
+	p.o.Do(func() {
+		if !p.warm {
+			close(p.ready)
+			return
+		}
+		go func() {
+			defer close(p.ready)
+
+			// Log
+			log.Debugf("[Start] FTP pool warm up of %d connections to %s", p.min, f.Addr)
+			defer func(now time.Time) {
+				log.Debugf("[End] FTP pool warm up of %d connections to %s in %s", p.min, f.Addr, time.Since(now))
+			}(time.Now())
+
+			var m sync.Mutex
+			var wg sync.WaitGroup
+			for i := 0; i < p.min; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if f.sem != nil {
+						f.sem <- struct{}{}
+						defer func() { <-f.sem }()
+					}
+					conn, err := f.Connect()
+					if err != nil {
+						log.Errorf("[FTP] error while warming up pool : %s", err.Error())
+						m.Lock()
+						if p.readyErr == nil {
+							p.readyErr = err
+						}
+						m.Unlock()
+						return
+					}
+					if !p.put(conn) {
+						conn.Quit()
+					}
+				}()
+			}
+			wg.Wait()
+		}()
+	})
+}
+
+// get returns an idle connection or nil if there's none
+func (p *pool) get() ServerConnexion {
+	for {
+		p.m.Lock()
+		if len(p.idle) == 0 {
+			p.m.Unlock()
+			return nil
+		}
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		p.m.Unlock()
+
+		// Make sure connections that stayed idle for a while are still alive
+		if time.Since(c.t) < poolIdleCheck {
+			return c.conn
+		}
+		if err := c.conn.NoOp(); err == nil {
+			return c.conn
+		}
+		c.conn.Quit()
+	}
+}
+
+// put stores a connection in the pool and returns false if the pool is full
+func (p *pool) put(conn ServerConnexion) bool {
+	p.m.Lock()
+	defer p.m.Unlock()
+	if len(p.idle) >= p.size {
+		return false
+	}
+	p.idle = append(p.idle, pooledConn{conn: conn, t: time.Now()})
+	return true
+}
+
+// close quits all idle connections
+func (p *pool) close() {
+	p.m.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.m.Unlock()
+	for _, c := range idle {
+		c.conn.Quit()
+	}
+}
+
+// reusable checks whether a connection can go back to the pool after an operation returned err
+// Only FTP permanent replies (file not found, permission denied, etc.) leave the connection in a known state
+func reusable(err error) bool {
+	if err == nil {
+		return true
+	}
+	e, ok := err.(*textproto.Error)
+	return ok && e.Code >= 500
+}
+
+// acquire returns a logged in connection, from the pool if possible
+func (f *FTP) acquire() (ServerConnexion, error) {
+	return f.acquireContext(context.Background())
+}
+
+// acquireContext returns a logged in connection, from the pool if possible, once the number of concurrent operations
+// allows it
+func (f *FTP) acquireContext(ctx context.Context) (conn ServerConnexion, err error) {
+	// Wait for a slot
+	if f.sem != nil {
+		select {
+		case f.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		defer func() {
+			if err != nil {
+				<-f.sem
+			}
+		}()
+	}
+
+	// Pool
+	if f.pool != nil {
+		f.pool.warmUp(f)
+		if conn = f.pool.get(); conn != nil {
+			return
+		}
+	}
+	return f.Connect()
+}
+
+// release gives a connection back to the pool or quits it, based on the error the operation returned, and frees
+// the operation slot
+func (f *FTP) release(conn ServerConnexion, err error) {
+	if f.sem != nil {
+		defer func() { <-f.sem }()
+	}
+	if f.pool != nil && reusable(err) && f.pool.put(conn) {
+		return
+	}
+	conn.Quit()
+}
+
+// WaitReady waits for the pool to have established its minimum number of connections
+// It starts the warm up if it hasn't been started yet, and returns right away when PoolWarmUp is off
+func (f *FTP) WaitReady(ctx context.Context) error {
+	if f.pool == nil {
+		return nil
+	}
+	f.pool.warmUp(f)
+	select {
+	case <-f.pool.ready:
+		return f.pool.readyErr
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close quits the pooled connections
+func (f *FTP) Close() {
+	if f.pool != nil {
+		f.pool.close()
+	}
+}
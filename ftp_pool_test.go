@@ -0,0 +1,108 @@
+package ftp_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_WaitReady(t *testing.T) {
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(getMockOfServerConnexion(getListOfFiles()), nil)
+	f := ftp.New(ftp.Configuration{PoolMinIdle: 2, PoolSize: 2, PoolWarmUp: true}, oDialer)
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := f.WaitReady(ctx); err != nil {
+		t.Fatalf("FTP.WaitReady() error = %v", err)
+	}
+	oDialer.AssertNumberOfCalls(t, "Dial", 2)
+
+	// Operations reuse the warm connections
+	f.List("", nil, "")
+	f.List("", nil, "")
+	oDialer.AssertNumberOfCalls(t, "Dial", 2)
+}
+
+func TestFTP_WarmUp(t *testing.T) {
+	var m sync.Mutex
+	var current, peak int
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Run(func(mock.Arguments) {
+		m.Lock()
+		current++
+		if current > peak {
+			peak = current
+		}
+		m.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		m.Lock()
+		current--
+		m.Unlock()
+	}).Return(getMockOfServerConnexion(getListOfFiles()), nil)
+
+	// Warm up connections take an operation slot
+	f := ftp.New(ftp.Configuration{MaxConcurrentOps: 1, PoolMinIdle: 3, PoolSize: 3, PoolWarmUp: true}, oDialer)
+	defer f.Close()
+	if err := f.WaitReady(context.Background()); err != nil {
+		t.Fatalf("FTP.WaitReady() error = %v", err)
+	}
+	oDialer.AssertNumberOfCalls(t, "Dial", 3)
+	if peak != 1 {
+		t.Errorf("%d connections established at once, want 1", peak)
+	}
+
+	// No warm up unless asked for
+	oDialer = &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(getMockOfServerConnexion(getListOfFiles()), nil)
+	f = ftp.New(ftp.Configuration{PoolMinIdle: 2, PoolSize: 2}, oDialer)
+	defer f.Close()
+	f.List("", nil, "")
+	if err := f.WaitReady(context.Background()); err != nil {
+		t.Fatalf("FTP.WaitReady() error = %v", err)
+	}
+	oDialer.AssertNumberOfCalls(t, "Dial", 1)
+}
+
+func TestFTP_MaxConcurrentOps(t *testing.T) {
+	var m sync.Mutex
+	var current, peak int
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", mock.Anything, mock.Anything).Return(func(path string, r io.Reader) error {
+		m.Lock()
+		current++
+		if current > peak {
+			peak = current
+		}
+		m.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		m.Lock()
+		current--
+		m.Unlock()
+		return nil
+	})
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	f := ftp.New(ftp.Configuration{MaxConcurrentOps: 2}, oDialer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.UploadReader(context.Background(), strings.NewReader("content"), "dst")
+		}()
+	}
+	wg.Wait()
+	if peak != 2 {
+		t.Errorf("max concurrent operations = %d, want 2", peak)
+	}
+}
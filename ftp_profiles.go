@@ -0,0 +1,152 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profiles represents a file describing several servers
+// Both YAML and JSON files are supported since JSON is valid YAML, and durations are written as strings such as "30s"
+type Profiles struct {
+	Defaults Profile            `yaml:"defaults"`
+	Servers  map[string]Profile `yaml:"servers"`
+}
+
+// Profile represents a server in a profiles file
+// Credentials are referenced rather than written in the file: the password is read from the PasswordEnv environment
+// variable or from the PasswordFile file
+type Profile struct {
+	Addr                   string        `yaml:"addr"`
+	BannerPattern          string        `yaml:"banner_pattern"`
+	CwdBeforeTransfer      bool          `yaml:"cwd_before_transfer"`
+	DataConnTimeout        time.Duration `yaml:"data_conn_timeout"`
+	DisableEPSV            bool          `yaml:"disable_epsv"`
+	DisableMLSD            bool          `yaml:"disable_mlsd"`
+	DisableSize            bool          `yaml:"disable_size"`
+	DisableUTF8            bool          `yaml:"disable_utf8"`
+	DualStackFallbackDelay time.Duration `yaml:"dual_stack_fallback_delay"`
+	LoginTimeout           time.Duration `yaml:"login_timeout"`
+	MaxConcurrentOps       int           `yaml:"max_concurrent_ops"`
+	MaxDownloadSize        int64         `yaml:"max_download_size"`
+	MaxUploadSize          int64         `yaml:"max_upload_size"`
+	PasswordEnv            string        `yaml:"password_env"`
+	PasswordFile           string        `yaml:"password_file"`
+	PoolMinIdle            int           `yaml:"pool_min_idle"`
+	PoolSize               int           `yaml:"pool_size"`
+	Quirks                 string        `yaml:"quirks"`
+	Root                   string        `yaml:"root"`
+	TLS                    string        `yaml:"tls"`
+	TLSInsecureSkipVerify  bool          `yaml:"tls_insecure_skip_verify"`
+	TLSServerName          string        `yaml:"tls_server_name"`
+	TLSSessionReuse        bool          `yaml:"tls_session_reuse"`
+	Timeout                time.Duration `yaml:"timeout"`
+	TransferTimeout        time.Duration `yaml:"transfer_timeout"`
+	TrashDir               string        `yaml:"trash_dir"`
+	Username               string        `yaml:"username"`
+}
+
+// LoadProfiles reads a profiles file
+func LoadProfiles(path string) (ps Profiles, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	if err = yaml.Unmarshal(b, &ps); err != nil {
+		err = fmt.Errorf("ftp: parsing %s failed: %s", path, err)
+	}
+	return
+}
+
+// Configuration converts a profile into a configuration, resolving its password
+func (p Profile) Configuration() (c Configuration, err error) {
+	c = Configuration{
+		Addr:                   p.Addr,
+		BannerPattern:          p.BannerPattern,
+		CwdBeforeTransfer:      p.CwdBeforeTransfer,
+		DataConnTimeout:        p.DataConnTimeout,
+		DisableEPSV:            p.DisableEPSV,
+		DisableMLSD:            p.DisableMLSD,
+		DisableSize:            p.DisableSize,
+		DisableUTF8:            p.DisableUTF8,
+		DualStackFallbackDelay: p.DualStackFallbackDelay,
+		LoginTimeout:           p.LoginTimeout,
+		MaxConcurrentOps:       p.MaxConcurrentOps,
+		MaxDownloadSize:        p.MaxDownloadSize,
+		MaxUploadSize:          p.MaxUploadSize,
+		PoolMinIdle:            p.PoolMinIdle,
+		PoolSize:               p.PoolSize,
+		Quirks:                 p.Quirks,
+		Root:                   p.Root,
+		TLS:                    p.TLS,
+		TLSSessionReuse:        p.TLSSessionReuse,
+		Timeout:                p.Timeout,
+		TransferTimeout:        p.TransferTimeout,
+		TrashDir:               p.TrashDir,
+		Username:               p.Username,
+	}
+
+	// TLS
+	switch p.TLS {
+	case "":
+	case TLSExplicit, TLSImplicit:
+		if p.TLSInsecureSkipVerify || p.TLSServerName != "" {
+			c.TLSConfig = &tls.Config{InsecureSkipVerify: p.TLSInsecureSkipVerify, ServerName: p.TLSServerName}
+		}
+	default:
+		return c, fmt.Errorf("ftp: unknown tls mode %s", p.TLS)
+	}
+
+	// Quirks and banner
+	if err = c.validate(); err != nil {
+		return
+	}
+
+	// Password
+	switch {
+	case p.PasswordEnv != "":
+		var ok bool
+		if c.Password, ok = os.LookupEnv(p.PasswordEnv); !ok {
+			return c, fmt.Errorf("ftp: password env var %s is not set", p.PasswordEnv)
+		}
+	case p.PasswordFile != "":
+		var b []byte
+		if b, err = ioutil.ReadFile(p.PasswordFile); err != nil {
+			return
+		}
+		c.Password = strings.TrimRight(string(b), "\r\n")
+	}
+	return
+}
+
+// Load registers the servers of a profiles file, the defaults of the file completing each server before the registry
+// defaults do
+func (r *Registry) Load(path string) error {
+	ps, err := LoadProfiles(path)
+	if err != nil {
+		return err
+	}
+	defaults, err := ps.Defaults.Configuration()
+	if err != nil {
+		return fmt.Errorf("ftp: defaults of %s: %s", path, err)
+	}
+
+	// Resolve all servers before registering any of them so that a faulty file changes nothing
+	cs := make(map[string]Configuration, len(ps.Servers))
+	for name, p := range ps.Servers {
+		c, err := p.Configuration()
+		if err != nil {
+			return fmt.Errorf("ftp: server %s of %s: %s", name, path, err)
+		}
+		cs[name] = withDefaults(c, defaults)
+	}
+	for name, c := range cs {
+		r.Register(name, c)
+	}
+	return nil
+}
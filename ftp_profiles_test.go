@@ -0,0 +1,72 @@
+package ftp_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+func TestRegistry_Load(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("FTP_TEST_PARTNER_A_PASSWORD", "secret")
+	defer os.Unsetenv("FTP_TEST_PARTNER_A_PASSWORD")
+	ioutil.WriteFile(filepath.Join(dir, "backup.pwd"), []byte("backup\n"), 0600)
+
+	// YAML
+	yml := filepath.Join(dir, "profiles.yml")
+	ioutil.WriteFile(yml, []byte(`
+defaults:
+  timeout: 30s
+  username: molotov
+servers:
+  partnerA:
+    addr: a.example.com:21
+    password_env: FTP_TEST_PARTNER_A_PASSWORD
+    root: /incoming
+    tls: explicit
+    max_upload_size: 1024
+  backupB:
+    addr: b.example.com:990
+    password_file: `+filepath.Join(dir, "backup.pwd")+`
+    tls: implicit
+    tls_insecure_skip_verify: true
+    username: backup
+`), 0644)
+	r := ftp.NewRegistry(ftp.Configuration{PoolSize: 2}, ftp.NewDefaultDialer())
+	if err := r.Load(yml); err != nil {
+		t.Fatalf("Registry.Load() error = %v", err)
+	}
+	a, _ := r.Configuration("partnerA")
+	if a.Addr != "a.example.com:21" || a.Password != "secret" || a.Root != "/incoming" || a.TLS != ftp.TLSExplicit ||
+		a.MaxUploadSize != 1024 || a.Timeout != 30*time.Second || a.Username != "molotov" || a.PoolSize != 2 {
+		t.Errorf("partnerA = %+v", a)
+	}
+	b, _ := r.Configuration("backupB")
+	if b.Password != "backup" || b.TLS != ftp.TLSImplicit || b.TLSConfig == nil || !b.TLSConfig.InsecureSkipVerify ||
+		b.Username != "backup" {
+		t.Errorf("backupB = %+v", b)
+	}
+
+	// JSON
+	js := filepath.Join(dir, "profiles.json")
+	ioutil.WriteFile(js, []byte(`{"servers": {"partnerC": {"addr": "c.example.com:21", "transfer_timeout": "1h"}}}`), 0644)
+	if err := r.Load(js); err != nil {
+		t.Fatalf("Registry.Load() error = %v", err)
+	}
+	if c, ok := r.Configuration("partnerC"); !ok || c.TransferTimeout != time.Hour {
+		t.Errorf("partnerC = %+v", c)
+	}
+
+	// Errors
+	ioutil.WriteFile(js, []byte(`{"servers": {"partnerD": {"password_env": "FTP_TEST_UNSET"}}}`), 0644)
+	if err := r.Load(js); err == nil {
+		t.Error("Registry.Load() should fail when the password env var is not set")
+	}
+	if _, ok := r.Configuration("partnerD"); ok {
+		t.Error("partnerD should not be registered")
+	}
+}
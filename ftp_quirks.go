@@ -0,0 +1,38 @@
+package ftp
+
+import "fmt"
+
+// Quirk presets
+const (
+	QuirksFileZilla = "filezilla"
+	QuirksIIS       = "iis"
+	QuirksMainframe = "mainframe"
+	QuirksVsftpd    = "vsftpd"
+)
+
+// quirks holds the switches each preset turns on
+// FileZilla Server and vsftpd require data connections to resume the TLS session of the control connection by
+// default, IIS behind NAT often answers EPSV with unreachable ports, and mainframes have datasets rather than paths,
+// unreliable SIZE replies and listings that are better parsed from LIST
+var quirks = map[string]Configuration{
+	QuirksFileZilla: {TLSSessionReuse: true},
+	QuirksIIS:       {DisableEPSV: true},
+	QuirksMainframe: {CwdBeforeTransfer: true, DisableEPSV: true, DisableMLSD: true, DisableSize: true, DisableUTF8: true},
+	QuirksVsftpd:    {TLSSessionReuse: true},
+}
+
+// withQuirks returns the configuration with the switches of its quirk preset turned on, in addition to the ones
+// already turned on
+func (c Configuration) withQuirks() (Configuration, error) {
+	q, ok := quirks[c.Quirks]
+	if !ok {
+		return c, fmt.Errorf("ftp: unknown quirks %s", c.Quirks)
+	}
+	c.CwdBeforeTransfer = c.CwdBeforeTransfer || q.CwdBeforeTransfer
+	c.DisableEPSV = c.DisableEPSV || q.DisableEPSV
+	c.DisableMLSD = c.DisableMLSD || q.DisableMLSD
+	c.DisableSize = c.DisableSize || q.DisableSize
+	c.DisableUTF8 = c.DisableUTF8 || q.DisableUTF8
+	c.TLSSessionReuse = c.TLSSessionReuse || q.TLSSessionReuse
+	return c, nil
+}
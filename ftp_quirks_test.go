@@ -0,0 +1,23 @@
+package ftp
+
+import "testing"
+
+func TestConfiguration_WithQuirks(t *testing.T) {
+	c, err := Configuration{Quirks: QuirksMainframe}.withQuirks()
+	if err != nil {
+		t.Fatalf("Configuration.withQuirks() error = %v", err)
+	}
+	if !c.CwdBeforeTransfer || !c.DisableEPSV || !c.DisableMLSD || !c.DisableSize || !c.DisableUTF8 || c.TLSSessionReuse {
+		t.Errorf("mainframe quirks = %+v", c)
+	}
+	if _, err = (Configuration{Quirks: "unknown"}).withQuirks(); err == nil {
+		t.Error("Configuration.withQuirks() with unknown quirks error = nil")
+	}
+
+	// Data connections share the session cache of the control connection
+	c, _ = Configuration{Quirks: QuirksVsftpd, TLS: TLSExplicit}.withQuirks()
+	d := (&defaultDialer{}).configure(c)
+	if a, b := d.clientTLSConfig("host:21"), d.clientTLSConfig("host:21"); a.ClientSessionCache == nil || a.ClientSessionCache != b.ClientSessionCache {
+		t.Error("TLS configurations don't share a session cache")
+	}
+}
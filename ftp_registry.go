@@ -0,0 +1,105 @@
+package ftp
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownServer is returned when looking up a name that hasn't been registered
+var ErrUnknownServer = errors.New("ftp: unknown server")
+
+// Registry holds several FTP instances by name
+// Instances are only created the first time they're looked up, from their configuration completed by the registry
+// defaults
+type Registry struct {
+	configs  map[string]Configuration
+	defaults Configuration
+	dialer   Dialer
+	ftps     map[string]*FTP
+	m        sync.Mutex
+}
+
+// NewRegistry creates a new registry with the defaults shared by all its servers
+func NewRegistry(defaults Configuration, dialer Dialer) *Registry {
+	return &Registry{
+		configs:  make(map[string]Configuration),
+		defaults: defaults,
+		dialer:   dialer,
+		ftps:     make(map[string]*FTP),
+	}
+}
+
+// Register adds a server to the registry, replacing any server with the same name
+// Fields left to their zero value take the value of the registry defaults, which means boolean fields can't be
+// disabled when the defaults enable them
+func (r *Registry) Register(name string, c Configuration) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if f, ok := r.ftps[name]; ok {
+		f.Close()
+		delete(r.ftps, name)
+	}
+	r.configs[name] = withDefaults(c, r.defaults)
+}
+
+// Get returns the FTP instance of a server, creating it if needed
+// It fails if the configuration of the server has an unknown quirks preset or an invalid banner pattern
+func (r *Registry) Get(name string) (*FTP, error) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	if f, ok := r.ftps[name]; ok {
+		return f, nil
+	}
+	c, ok := r.configs[name]
+	if !ok {
+		return nil, ErrUnknownServer
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	f := New(c, r.dialer)
+	r.ftps[name] = f
+	return f, nil
+}
+
+// Configuration returns the configuration of a server, defaults included
+func (r *Registry) Configuration(name string) (c Configuration, ok bool) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	c, ok = r.configs[name]
+	return
+}
+
+// Names returns the sorted names of the registered servers
+func (r *Registry) Names() (names []string) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for name := range r.configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Close closes the FTP instances that have been created
+func (r *Registry) Close() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	for name, f := range r.ftps {
+		f.Close()
+		delete(r.ftps, name)
+	}
+}
+
+// withDefaults sets the fields of a configuration that have their zero value to the value of the defaults
+func withDefaults(c, defaults Configuration) Configuration {
+	v, d := reflect.ValueOf(&c).Elem(), reflect.ValueOf(defaults)
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).IsZero() {
+			v.Field(i).Set(d.Field(i))
+		}
+	}
+	return c
+}
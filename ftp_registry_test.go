@@ -0,0 +1,53 @@
+package ftp_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRegistry(t *testing.T) {
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(getMockOfServerConnexion(nil), nil)
+	r := ftp.NewRegistry(ftp.Configuration{Timeout: time.Minute, Username: "molotov"}, oDialer)
+	defer r.Close()
+	r.Register("partnerA", ftp.Configuration{Addr: "a:21"})
+	r.Register("backupB", ftp.Configuration{Addr: "b:21", Username: "backup"})
+
+	if want := []string{"backupB", "partnerA"}; !reflect.DeepEqual(r.Names(), want) {
+		t.Errorf("Registry.Names() = %v, want %v", r.Names(), want)
+	}
+
+	// Defaults
+	a, err := r.Get("partnerA")
+	if err != nil {
+		t.Fatalf("Registry.Get() error = %v", err)
+	}
+	if a.Addr != "a:21" || a.Timeout != time.Minute || a.Username != "molotov" {
+		t.Errorf("partnerA = %s, %s, %s", a.Addr, a.Timeout, a.Username)
+	}
+	if b, _ := r.Get("backupB"); b.Username != "backup" {
+		t.Errorf("backupB username = %s, want backup", b.Username)
+	}
+
+	// Lazy construction
+	if again, _ := r.Get("partnerA"); again != a {
+		t.Error("Registry.Get() created a new instance")
+	}
+	if _, err = r.Get("unknown"); err != ftp.ErrUnknownServer {
+		t.Errorf("Registry.Get() error = %v, want %v", err, ftp.ErrUnknownServer)
+	}
+
+	// Invalid configurations fail instead of panicking
+	r.Register("quirky", ftp.Configuration{Quirks: "unknown"})
+	r.Register("banner", ftp.Configuration{BannerPattern: "("})
+	for _, name := range []string{"quirky", "banner"} {
+		if _, err = r.Get(name); err == nil {
+			t.Errorf("Registry.Get(%s) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package ftp
+
+import (
+	"io"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// resilientReader reads a remote file and transparently reconnects and resumes at the current offset when the
+// connection breaks
+// size is the size of the file when it's known, a transfer ending before it being a broken one as well
+type resilientReader struct {
+	attempts int
+	conn     ServerConnexion
+	f        *FTP
+	offset   int64
+	override *RetryPolicy
+	r        io.ReadCloser
+	size     int64
+	sized    bool
+	src      string
+}
+
+// ResilientReader returns a reader of a remote file that survives connection errors: on failure it reconnects and
+// resumes the download where it stopped, as many times in a row as the retry policy allows
+// Only the retry policy options are taken into account
+func (f *FTP) ResilientReader(src string, opts ...TransferOption) (io.ReadCloser, error) {
+	r := &resilientReader{f: f, override: newTransferOptions(opts).retry, size: -1, src: src}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *resilientReader) open() (err error) {
+	if r.conn, err = r.f.acquire(); err != nil {
+		return
+	}
+
+	// Get the size, if possible, before the first transfer
+	if !r.sized && r.f.sizeSupported(r.conn) {
+		if n, errSize := r.f.fileSize(r.conn, r.src); errSize == nil {
+			r.size = n
+		}
+	}
+	r.sized = true
+
+	if r.r, err = r.f.retrFrom(r.conn, r.src, r.offset); err != nil {
+		r.f.release(r.conn, err)
+		r.conn = nil
+		return
+	}
+	return
+}
+
+// drop closes a broken transfer and its connection
+func (r *resilientReader) drop(err error) {
+	if r.r != nil {
+		r.r.Close()
+		r.r = nil
+	}
+	if r.conn != nil {
+		r.f.release(r.conn, err)
+		r.conn = nil
+	}
+}
+
+// Read implements the io.Reader interface
+func (r *resilientReader) Read(p []byte) (n int, err error) {
+	for {
+		// Reconnect
+		if r.r == nil {
+			if err = r.open(); err != nil {
+				if !r.retry(err) {
+					return 0, err
+				}
+				continue
+			}
+		}
+
+		// Read
+		n, err = r.r.Read(p)
+		r.offset += int64(n)
+		if n > 0 {
+			r.attempts = 0
+		}
+
+		// The data connection may have been closed early without error
+		if err == io.EOF && r.size >= 0 && r.offset < r.size {
+			err = io.ErrUnexpectedEOF
+		}
+		if err == nil || err == io.EOF || !retryable(err) {
+			return
+		}
+
+		// The connection is broken, the next read will resume at the current offset
+		r.drop(err)
+		if n > 0 {
+			return n, nil
+		}
+		if !r.retry(err) {
+			return
+		}
+	}
+}
+
+// retry checks whether another attempt can be made after err and waits before it
+func (r *resilientReader) retry(err error) bool {
+	p := r.f.retryPolicy(transferOptions{retry: r.override})
+	if !retryable(err) || r.attempts+1 >= p.Attempts {
+		return false
+	}
+	r.attempts++
+	log.Debugf("[FTP] resuming %s at %d after error (attempt %d/%d) : %s", r.src, r.offset, r.attempts+1, p.Attempts, err.Error())
+	time.Sleep(p.Delay)
+	return true
+}
+
+// Close implements the io.Closer interface
+func (r *resilientReader) Close() (err error) {
+	if r.r == nil {
+		return nil
+	}
+	err = r.r.Close()
+	r.f.release(r.conn, err)
+	r.r, r.conn = nil, nil
+	return
+}
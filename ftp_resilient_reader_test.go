@@ -0,0 +1,42 @@
+package ftp_test
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+func TestFTP_ResilientReader(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/in/movie.mp4": "0123456789"})
+	oFtp := s.ftp(ftp.Configuration{})
+
+	// The transfer stops early twice without error
+	s.drop("/in/movie.mp4", 4, 3)
+	r, err := oFtp.ResilientReader("/in/movie.mp4", ftp.WithRetryPolicy(ftp.RetryPolicy{Attempts: 2}))
+	if err != nil {
+		t.Fatalf("base.ResilientReader() error = %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading error = %v", err)
+	}
+	r.Close()
+	if string(b) != "0123456789" {
+		t.Errorf("read %q, want %q", b, "0123456789")
+	}
+	if n := s.count("REST"); n != 2 {
+		t.Errorf("%d resumes, want 2", n)
+	}
+
+	// Attempts run out
+	s.drop("/in/movie.mp4", 4, 0)
+	if r, err = oFtp.ResilientReader("/in/movie.mp4", ftp.WithRetryPolicy(ftp.RetryPolicy{Attempts: 2})); err != nil {
+		t.Fatalf("base.ResilientReader() error = %v", err)
+	}
+	defer r.Close()
+	if b, err = ioutil.ReadAll(r); err != io.ErrUnexpectedEOF {
+		t.Errorf("reading = %q, %v, want %v", b, err, io.ErrUnexpectedEOF)
+	}
+}
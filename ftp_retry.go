@@ -0,0 +1,57 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"syscall"
+	"time"
+)
+
+// defaultRetryPolicy is used when no policy is configured
+var defaultRetryPolicy = RetryPolicy{Attempts: 3, Delay: time.Second}
+
+// RetryPolicy represents how failed operations are retried
+type RetryPolicy struct {
+	// Attempts is the number of times an operation is tried, the first one included, so that 1 disables retries
+	Attempts int `json:"attempts"`
+	// Delay is how long to wait between two attempts
+	Delay time.Duration `json:"delay"`
+}
+
+// retryPolicy returns the policy of a call: the one given with WithRetryPolicy or WithNoRetry, then the one of the
+// instance, then the default one
+func (f *FTP) retryPolicy(o transferOptions) RetryPolicy {
+	if o.retry != nil {
+		return *o.retry
+	}
+	if f.Retry.Attempts > 0 {
+		return f.Retry
+	}
+	return defaultRetryPolicy
+}
+
+// retryable checks whether an error is transient and worth retrying, which is only the case of connection errors,
+// transfers cut short and FTP transient replies (4xx)
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var e *textproto.Error
+	if errors.As(err, &e) {
+		return e.Code >= 400 && e.Code < 500
+	}
+	// System errors implement net.Error as well, so local ones such as those of files are ruled out
+	var oe *net.OpError
+	if errors.As(err, &oe) {
+		return true
+	}
+	var ne net.Error
+	var errno syscall.Errno
+	return errors.As(err, &ne) && !errors.As(err, &errno)
+}
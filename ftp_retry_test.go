@@ -0,0 +1,53 @@
+package ftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRetryable(t *testing.T) {
+	for _, v := range []struct {
+		err  error
+		want bool
+	}{
+		{err: nil},
+		{err: io.EOF},
+		{err: context.Canceled},
+		{err: context.DeadlineExceeded},
+		{err: ErrDecryption},
+		{err: ErrIncompleteTransfer},
+		{err: ErrInvalidPath},
+		{err: ErrUnexpectedBanner},
+		{err: &os.PathError{Op: "read", Path: "file", Err: syscall.EIO}},
+		{err: &textproto.Error{Code: 550, Msg: "No such file"}},
+		{err: &textproto.Error{Code: 426, Msg: "Transfer aborted"}, want: true},
+		{err: io.ErrUnexpectedEOF, want: true},
+		{err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("broken")}, want: true},
+	} {
+		if got := retryable(v.err); got != v.want {
+			t.Errorf("retryable(%v) = %v, want %v", v.err, got, v.want)
+		}
+	}
+}
+
+func TestFTP_RetryPolicy(t *testing.T) {
+	f := &FTP{}
+	if p := f.retryPolicy(transferOptions{}); p != defaultRetryPolicy {
+		t.Errorf("default policy = %+v, want %+v", p, defaultRetryPolicy)
+	}
+	f.Retry = RetryPolicy{Attempts: 5}
+	if p := f.retryPolicy(transferOptions{}); p != f.Retry {
+		t.Errorf("instance policy = %+v, want %+v", p, f.Retry)
+	}
+	if p := f.retryPolicy(newTransferOptions([]TransferOption{WithNoRetry()})); p.Attempts != 0 {
+		t.Errorf("no retry policy = %+v, want no attempts", p)
+	}
+}
@@ -0,0 +1,37 @@
+package ftp
+
+import "strings"
+
+// iac is the telnet "interpret as command" byte, which the control connection may interpret unless it's doubled
+const iac = 0xff
+
+// SanitizeName makes a file name safe to be used in a path: CR, LF, NUL and telnet IAC bytes are replaced with "_" and
+// surrounding spaces are trimmed, so names differing only by them end up the same. A name starting with a dash is
+// prefixed with "./" so that it isn't taken as an option
+func SanitizeName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		switch c {
+		case '\r', '\n', 0, iac:
+			b[i] = '_'
+		}
+	}
+	return escapeLeading(strings.TrimSpace(string(b)))
+}
+
+// EscapePath makes a path safe to be sent in a command: a path containing CR, LF or NUL is rejected since it would
+// break the command, telnet IAC bytes are doubled and a leading dash or space is escaped with "./"
+// Connexions of the default dialer escape the paths of all their commands, so it's only needed by custom dialers
+func EscapePath(p string) (string, error) {
+	if strings.ContainsAny(p, "\r\n\x00") {
+		return "", ErrInvalidPath
+	}
+	return escapeLeading(strings.Replace(p, string([]byte{iac}), string([]byte{iac, iac}), -1)), nil
+}
+
+func escapeLeading(p string) string {
+	if strings.HasPrefix(p, "-") || strings.HasPrefix(p, " ") {
+		return "./" + p
+	}
+	return p
+}
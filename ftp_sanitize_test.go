@@ -0,0 +1,73 @@
+package ftp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "report.xml", want: "report.xml"},
+		{name: " spaced name.xml ", want: "spaced name.xml"},
+		{name: "-rf", want: "./-rf"},
+		{name: "evil\r\nDELE x", want: "evil__DELE x"},
+		{name: "nul\x00byte", want: "nul_byte"},
+		{name: "iac\xffbyte", want: "iac_byte"},
+	}
+	for _, tt := range tests {
+		if got := ftp.SanitizeName(tt.name); got != tt.want {
+			t.Errorf("ftp.SanitizeName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    string
+		wantErr error
+	}{
+		{path: "drop/report.xml", want: "drop/report.xml"},
+		{path: "-rf/report.xml", want: "./-rf/report.xml"},
+		{path: " drop", want: "./ drop"},
+		{path: "iac\xff.xml", want: "iac\xff\xff.xml"},
+		{path: "evil\r\nDELE x", wantErr: ftp.ErrInvalidPath},
+	}
+	for _, tt := range tests {
+		got, err := ftp.EscapePath(tt.path)
+		if err != tt.wantErr || got != tt.want {
+			t.Errorf("ftp.EscapePath(%q) = %q, %v, want %q, %v", tt.path, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFTP_EscapedCommands(t *testing.T) {
+	s := newFakeServer(t, nil)
+	oFtp := s.ftp(ftp.Configuration{})
+
+	if err := oFtp.CreateFile("-report.xml", strings.NewReader("report")); err != nil {
+		t.Fatalf("base.CreateFile() error = %v", err)
+	}
+	if err := oFtp.Rename("-report.xml", "iac\xff.xml"); err != nil {
+		t.Fatalf("base.Rename() error = %v", err)
+	}
+	if err := oFtp.Remove("evil\r\nDELE x"); !errors.Is(err, ftp.ErrInvalidPath) {
+		t.Fatalf("base.Remove() error = %v, want %v", err, ftp.ErrInvalidPath)
+	}
+
+	cmds := strings.Join(s.commands(), "\n")
+	for _, want := range []string{"STOR ./-report.xml", "RNFR ./-report.xml", "RNTO iac\xff\xff.xml"} {
+		if !strings.Contains(cmds, want) {
+			t.Errorf("commands = %q, want %q", cmds, want)
+		}
+	}
+	if n := s.count("DELE"); n != 0 {
+		t.Errorf("%d DELE sent, want none", n)
+	}
+}
@@ -1,7 +1,11 @@
 package ftp
 
 import (
+	"bufio"
 	"io"
+	"net"
+	"net/textproto"
+	"time"
 
 	"github.com/jlaffaye/ftp"
 )
@@ -9,13 +13,197 @@ import (
 type ServerConnexion interface {
 	Login(sUsername string, sPwd string) error
 	Retr(path string) (*ftp.Response, error)
+	RetrFrom(path string, offset uint64) (*ftp.Response, error)
 	FileSize(path string) (int64, error)
 	Stor(path string, oReader io.Reader) error
+	StorFrom(path string, oReader io.Reader, offset uint64) error
+	Append(path string, oReader io.Reader) error
 	MakeDir(sSource string) error
+	ChangeDir(sPath string) error
+	CurrentDir() (string, error)
 	RemoveDir(sSource string) error
 	RemoveDirRecur(sSource string) error
 	Rename(sSource string, sDestination string) error
 	Delete(oath string) error
+	NoOp() error
 	Quit() error
 	List(sPath string) ([]*ftp.Entry, error)
+	Features() map[string]string
+}
+
+// deadliner is implemented by connexions whose control connection deadline can be set
+type deadliner interface {
+	SetDeadline(t time.Time) error
+}
+
+// banner is implemented by connexions knowing the greeting of the server
+type banner interface {
+	Banner() string
+}
+
+// allocator is implemented by connexions able to reserve space for an upload
+type allocator interface {
+	Allocate(size int64) error
+}
+
+// serverConnexion adds what the control connection transcript tells us to the underlying client
+// conn is the TCP connection, and control the connection commands go through, which is only known when it's not
+// upgraded to TLS by the client itself
+type serverConnexion struct {
+	*ftp.ServerConn
+	conn    net.Conn
+	control net.Conn
+	r       *controlRecorder
+}
+
+// SetDeadline sets the deadline of the control connection
+func (c *serverConnexion) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Allocate sends ALLO, which the client doesn't support, directly on the control connection
+// The server only speaks when spoken to, so the client has nothing buffered between commands and the reply can be read
+// here. The exchange goes through the recorder like the ones of the client so that the transcript stays complete
+// It isn't possible in explicit TLS mode since the client owns the TLS connection
+func (c *serverConnexion) Allocate(size int64) error {
+	if c.control == nil {
+		return ErrUnsupported
+	}
+	w := bufio.NewWriter(io.MultiWriter(c.control, c.r))
+	if err := textproto.NewWriter(w).PrintfLine("ALLO %d", size); err != nil {
+		return err
+	}
+	_, _, err := textproto.NewReader(bufio.NewReaderSize(io.TeeReader(c.control, c.r), 512)).ReadResponse(2)
+	return err
+}
+
+// Banner returns the greeting of the server
+func (c *serverConnexion) Banner() string {
+	return c.r.Banner()
+}
+
+// Features returns the features advertised by the server during login
+func (c *serverConnexion) Features() map[string]string {
+	return c.r.Features()
+}
+
+// The commands taking a path escape it with EscapePath, so that no name can break or inject a command
+
+// Retr downloads a file
+func (c *serverConnexion) Retr(p string) (*ftp.Response, error) {
+	p, err := EscapePath(p)
+	if err != nil {
+		return nil, err
+	}
+	return c.ServerConn.Retr(p)
+}
+
+// RetrFrom downloads a file starting at offset
+func (c *serverConnexion) RetrFrom(p string, offset uint64) (*ftp.Response, error) {
+	p, err := EscapePath(p)
+	if err != nil {
+		return nil, err
+	}
+	return c.ServerConn.RetrFrom(p, offset)
+}
+
+// FileSize returns the size of a file
+func (c *serverConnexion) FileSize(p string) (int64, error) {
+	p, err := EscapePath(p)
+	if err != nil {
+		return 0, err
+	}
+	return c.ServerConn.FileSize(p)
+}
+
+// Stor uploads a file
+func (c *serverConnexion) Stor(p string, r io.Reader) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.Stor(p, r)
+}
+
+// StorFrom uploads a file starting at offset
+func (c *serverConnexion) StorFrom(p string, r io.Reader, offset uint64) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.StorFrom(p, r, offset)
+}
+
+// Append appends to a file
+func (c *serverConnexion) Append(p string, r io.Reader) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.Append(p, r)
+}
+
+// MakeDir creates a folder
+func (c *serverConnexion) MakeDir(p string) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.MakeDir(p)
+}
+
+// ChangeDir changes the working directory
+func (c *serverConnexion) ChangeDir(p string) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.ChangeDir(p)
+}
+
+// RemoveDir removes an empty folder
+func (c *serverConnexion) RemoveDir(p string) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.RemoveDir(p)
+}
+
+// RemoveDirRecur removes a folder and its content
+func (c *serverConnexion) RemoveDirRecur(p string) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.RemoveDirRecur(p)
+}
+
+// Rename renames a file
+func (c *serverConnexion) Rename(from, to string) (err error) {
+	if from, err = EscapePath(from); err != nil {
+		return
+	}
+	if to, err = EscapePath(to); err != nil {
+		return
+	}
+	return c.ServerConn.Rename(from, to)
+}
+
+// Delete deletes a file
+func (c *serverConnexion) Delete(p string) error {
+	p, err := EscapePath(p)
+	if err != nil {
+		return err
+	}
+	return c.ServerConn.Delete(p)
+}
+
+// List lists a folder
+func (c *serverConnexion) List(p string) ([]*ftp.Entry, error) {
+	p, err := EscapePath(p)
+	if err != nil {
+		return nil, err
+	}
+	return c.ServerConn.List(p)
 }
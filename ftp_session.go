@@ -0,0 +1,175 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/jlaffaye/ftp"
+)
+
+// Session represents a connection checked out for a sequence of related commands, which run on the same connection
+// and therefore share its state, such as the working directory
+// A session must be used by one goroutine at a time, and released once done
+type Session struct {
+	conn    ServerConnexion
+	err     error
+	f       *FTP
+	initial string
+	moved   bool
+	o       sync.Once
+}
+
+// AcquireSession checks out a connection, from the pool if possible, dedicated to the caller until Release is called
+func (f *FTP) AcquireSession(ctx context.Context) (*Session, error) {
+	conn, err := f.acquireContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Session{conn: conn, f: f}, nil
+}
+
+// track keeps the errors after which the connection can't be reused
+func (s *Session) track(err error) error {
+	if !reusable(err) && s.err == nil {
+		s.err = err
+	}
+	return err
+}
+
+// AppendReader appends a reader content to a destination, creating it if it doesn't exist
+func (s *Session) AppendReader(ctx context.Context, r io.Reader, dst string, opts ...TransferOption) error {
+	return s.track(s.f.appendConn(ctx, s.conn, r, dst, newTransferOptions(opts)))
+}
+
+// ChangeDir changes the working directory
+func (s *Session) ChangeDir(dir string) error {
+	// Keep the initial working directory so that it's restored before the connection goes back to the pool
+	if !s.moved {
+		initial, err := s.conn.CurrentDir()
+		if err != nil {
+			return s.track(err)
+		}
+		s.initial, s.moved = initial, true
+	}
+	return s.track(s.conn.ChangeDir(dir))
+}
+
+// CreateFile creates a file with the content of a reader, like FTP.CreateFile
+func (s *Session) CreateFile(p string, r io.Reader) error {
+	if len(p) == 0 {
+		return nil
+	}
+	return s.track(s.f.createFile(s.conn, p, r))
+}
+
+// CurrentDir returns the working directory
+func (s *Session) CurrentDir() (string, error) {
+	dir, err := s.conn.CurrentDir()
+	return dir, s.track(err)
+}
+
+// Delete removes a file, or moves it to the trash folder if there's one, like FTP.Remove
+func (s *Session) Delete(p string) error {
+	return s.track(s.f.remove(s.conn, p))
+}
+
+// Download downloads a file to a local path, like FTP.Download
+func (s *Session) Download(ctx context.Context, src, dst string, opts ...TransferOption) error {
+	return s.track(s.f.download(ctx, s.conn, src, dst, newTransferOptions(opts)))
+}
+
+// DownloadReader returns the reader of the download of a file, which must be closed before the next command
+func (s *Session) DownloadReader(src string) (io.ReadCloser, error) {
+	r, err := s.f.retrFrom(s.conn, src, 0)
+	return r, s.track(err)
+}
+
+// Exists checks whether a file exists
+func (s *Session) Exists(p string) (bool, error) {
+	b, err := s.f.exists(s.conn, p)
+	if s.track(err) != nil && reusable(err) {
+		return false, nil
+	}
+	return b, err
+}
+
+// ExistsDir checks whether a folder exists, the working directory being left as is
+func (s *Session) ExistsDir(p string) (bool, error) {
+	b, err := s.f.existsDir(s.conn, p)
+	return b, s.track(err)
+}
+
+// FileSize returns the size of a file, from the listing of its folder when SIZE is disabled
+func (s *Session) FileSize(p string) (int64, error) {
+	n, err := s.f.fileSize(s.conn, p)
+	return n, s.track(err)
+}
+
+// List lists a folder
+func (s *Session) List(p string) ([]*ftp.Entry, error) {
+	entries, err := s.conn.List(p)
+	return entries, s.track(err)
+}
+
+// MakeDir creates a folder
+func (s *Session) MakeDir(p string) error {
+	return s.track(s.conn.MakeDir(p))
+}
+
+// RemoveDir removes an empty folder
+func (s *Session) RemoveDir(p string) error {
+	return s.track(s.conn.RemoveDir(p))
+}
+
+// RemoveDirRecur removes a folder and its content
+func (s *Session) RemoveDirRecur(p string) error {
+	return s.track(s.conn.RemoveDirRecur(p))
+}
+
+// Rename renames a file or a folder
+func (s *Session) Rename(from, to string) error {
+	return s.track(s.conn.Rename(from, to))
+}
+
+// Upload uploads a local file to a destination, like FTP.Upload
+func (s *Session) Upload(ctx context.Context, src, dst string, opts ...TransferOption) (err error) {
+	return s.f.upload(ctx, src, dst, opts, func() (ServerConnexion, func(error), error) {
+		return s.conn, func(err error) { s.track(err) }, nil
+	}, s.UploadReader)
+}
+
+// Touch creates an empty file, like FTP.Touch
+func (s *Session) Touch(p string) error {
+	return s.CreateFile(p, bytes.NewReader(nil))
+}
+
+// UploadReader uploads a reader content to a destination, like FTP.UploadReader but without retries since they would
+// need another connection
+func (s *Session) UploadReader(ctx context.Context, r io.Reader, dst string, opts ...TransferOption) (err error) {
+	o := newTransferOptions(opts)
+	o.maxSize = s.f.MaxUploadSize
+
+	// Compress, transform and encrypt
+	var closers []io.Closer
+	defer func() { closeAll(closers) }()
+	if r, dst, closers, err = encodeUpload(r, dst, o); err != nil {
+		return
+	}
+	return s.track(s.f.storConn(ctx, s.conn, r, dst, false, 0, o))
+}
+
+// Release gives the connection back, after restoring its initial working directory, and must be called once
+// Later calls do nothing
+func (s *Session) Release() {
+	s.o.Do(func() {
+		// A connection whose working directory can't be restored is not reused
+		if s.moved && s.err == nil {
+			if err := s.conn.ChangeDir(s.initial); err != nil {
+				s.err = errDirty
+			}
+		}
+		s.f.release(s.conn, s.err)
+	})
+}
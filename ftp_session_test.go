@@ -0,0 +1,164 @@
+package ftp_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_AcquireSession(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("CurrentDir").Return("/home", nil)
+	oConnexion.On("ChangeDir", "/drop").Return(nil)
+	oConnexion.On("ChangeDir", "/home").Return(nil)
+	oConnexion.On("Stor", mock.Anything, mock.Anything).Return(nil)
+	oConnexion.On("Rename", "a.xml.tmp", "a.xml").Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{PoolSize: 1}, oDialer)
+	defer oFtp.Close()
+
+	s, err := oFtp.AcquireSession(context.Background())
+	if err != nil {
+		t.Fatalf("base.AcquireSession() error = %v", err)
+	}
+	if err = s.ChangeDir("/drop"); err != nil {
+		t.Fatalf("Session.ChangeDir() error = %v", err)
+	}
+	if err = s.UploadReader(context.Background(), strings.NewReader("content"), "a.xml.tmp"); err != nil {
+		t.Fatalf("Session.UploadReader() error = %v", err)
+	}
+	if err = s.Rename("a.xml.tmp", "a.xml"); err != nil {
+		t.Fatalf("Session.Rename() error = %v", err)
+	}
+	s.Release()
+	s.Release()
+
+	// The working directory is restored and the connection reused
+	oConnexion.AssertCalled(t, "ChangeDir", "/home")
+	if _, err = oFtp.AcquireSession(context.Background()); err != nil {
+		t.Fatalf("base.AcquireSession() error = %v", err)
+	}
+	oDialer.AssertNumberOfCalls(t, "Dial", 1)
+	oConnexion.AssertNumberOfCalls(t, "Quit", 0)
+}
+
+func TestSession_Transfers(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/drop/a.xml": "a"})
+	oFtp := s.ftp(ftp.Configuration{MaxUploadSize: 64, TrashDir: "/trash"})
+	defer oFtp.Close()
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	session, err := oFtp.AcquireSession(ctx)
+	if err != nil {
+		t.Fatalf("base.AcquireSession() error = %v", err)
+	}
+	defer session.Release()
+
+	// Upload with the options of the instance
+	src := filepath.Join(dir, "b.xml")
+	if err = ioutil.WriteFile(src, []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = session.Upload(ctx, src, "/drop/b.xml", ftp.WithGzip()); err != nil {
+		t.Fatalf("Session.Upload() error = %v", err)
+	}
+	if c, ok := s.file("/drop/b.xml.gz"); !ok {
+		t.Error("compressed file was not uploaded")
+	} else if r, err := gzip.NewReader(strings.NewReader(c)); err != nil {
+		t.Errorf("uploaded file is not compressed: %v", err)
+	} else if b, _ := ioutil.ReadAll(r); string(b) != "b" {
+		t.Errorf("uploaded content = %q, want %q", b, "b")
+	}
+	if err = session.UploadReader(ctx, strings.NewReader(strings.Repeat("c", 100)), "/drop/c.xml"); err != ftp.ErrFileTooLarge {
+		t.Errorf("Session.UploadReader() error = %v, want %v", err, ftp.ErrFileTooLarge)
+	}
+
+	// Append
+	if err = session.AppendReader(ctx, strings.NewReader("bc"), "/drop/a.xml"); err != nil {
+		t.Fatalf("Session.AppendReader() error = %v", err)
+	}
+
+	// Exists
+	for p, want := range map[string]bool{"/drop/a.xml": true, "/drop/c.xml": false} {
+		if ok, err := session.Exists(p); err != nil || ok != want {
+			t.Errorf("Session.Exists(%s) = %v, %v, want %v, nil", p, ok, err, want)
+		}
+	}
+
+	// Download
+	dst := filepath.Join(dir, "a.xml")
+	if err = session.Download(ctx, "/drop/a.xml", dst); err != nil {
+		t.Fatalf("Session.Download() error = %v", err)
+	}
+	if b, _ := ioutil.ReadFile(dst); string(b) != "abc" {
+		t.Errorf("downloaded content = %q, want %q", b, "abc")
+	}
+
+	// Delete goes through the trash
+	if err = session.Delete("/drop/a.xml"); err != nil {
+		t.Fatalf("Session.Delete() error = %v", err)
+	}
+	if _, ok := s.file("/drop/a.xml"); ok {
+		t.Error("deleted file is still there")
+	}
+	if n := s.count("DELE /drop/a.xml"); n != 0 {
+		t.Errorf("%d DELE of the file, want 0", n)
+	}
+	if n := s.count("RNTO /trash/"); n != 1 {
+		t.Errorf("%d moves to the trash, want 1", n)
+	}
+
+	// Everything ran on the session's connection
+	if n := s.count("USER"); n != 1 {
+		t.Errorf("%d logins, want 1", n)
+	}
+}
+
+func TestSession_Operations(t *testing.T) {
+	oConnexion := getMockOfServerConnexion([]*base.Entry{{Name: "a.xml", Size: 3, Type: base.EntryTypeFile}}).(*mocks.ServerConnexion)
+	oConnexion.On("CurrentDir").Return("/home", nil)
+	oConnexion.On("ChangeDir", "/drop").Return(nil)
+	oConnexion.On("ChangeDir", "/missing").Return(&textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such directory"})
+	oConnexion.On("ChangeDir", "/home").Return(nil)
+	oConnexion.On("Stor", "/drop/a.xml.done", mock.Anything).Return(nil)
+	oConnexion.On("RemoveDirRecur", "/old").Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{DisableSize: true}, oDialer)
+
+	s, err := oFtp.AcquireSession(context.Background())
+	if err != nil {
+		t.Fatalf("base.AcquireSession() error = %v", err)
+	}
+	defer s.Release()
+
+	// Folders
+	for p, want := range map[string]bool{"/drop": true, "/missing": false} {
+		if ok, err := s.ExistsDir(p); err != nil || ok != want {
+			t.Errorf("Session.ExistsDir(%s) = %v, %v, want %v, nil", p, ok, err, want)
+		}
+	}
+	if err = s.RemoveDirRecur("/old"); err != nil {
+		t.Errorf("Session.RemoveDirRecur() error = %v", err)
+	}
+
+	// Files
+	if err = s.Touch("/drop/a.xml.done"); err != nil {
+		t.Errorf("Session.Touch() error = %v", err)
+	}
+	if n, err := s.FileSize("/drop/a.xml"); err != nil || n != 3 {
+		t.Errorf("Session.FileSize() = %d, %v, want 3, nil", n, err)
+	}
+	oConnexion.AssertNotCalled(t, "FileSize", mock.Anything)
+}
@@ -0,0 +1,39 @@
+package ftp
+
+import (
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// Operations, as used to configure slow thresholds
+const (
+	OpAppend   = "append"
+	OpConnect  = "connect"
+	OpDownload = "download"
+	OpExists   = "exists"
+	OpFileSize = "file_size"
+	OpList     = "list"
+	OpRemove   = "remove"
+	OpUpload   = "upload"
+)
+
+// slowThreshold returns the threshold of an operation, which defaults to the global one
+func (f *FTP) slowThreshold(op string) time.Duration {
+	if d, ok := f.SlowThresholds[op]; ok {
+		return d
+	}
+	return f.SlowThreshold
+}
+
+// checkSlow warns and calls the OnSlowOperation hook when an operation took longer than its threshold
+func (f *FTP) checkSlow(op, path string, elapsed time.Duration) {
+	d := f.slowThreshold(op)
+	if d <= 0 || elapsed < d {
+		return
+	}
+	log.Warnf("[FTP] slow %s of %s on %s: %s exceeds %s", op, path, f.Addr, elapsed, d)
+	if f.OnSlowOperation != nil {
+		f.OnSlowOperation(op, path, elapsed)
+	}
+}
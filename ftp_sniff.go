@@ -0,0 +1,35 @@
+package ftp
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+// sniffLen is the number of bytes content types are detected from
+const sniffLen = 512
+
+// TransferResult holds what's learnt about the content of a transfer
+type TransferResult struct {
+	ContentType string
+}
+
+// WithContentSniffing detects the MIME type of downloads from their first 512 bytes and stores it in res, so that
+// mislabeled files can be rejected. DownloadReader stores it before returning, DownloadPipe before the first read
+// returns and Download once the file is written
+func WithContentSniffing(res *TransferResult) TransferOption {
+	return func(o *transferOptions) {
+		o.sniff = res
+	}
+}
+
+// sniff detects the content type of r and returns a reader of the whole content
+func sniff(r io.Reader, res *TransferResult) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, sniffLen)
+	b, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	res.ContentType = http.DetectContentType(b)
+	return br, nil
+}
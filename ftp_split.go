@@ -0,0 +1,240 @@
+package ftp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	log "github.com/molotovtv/go-logger"
+)
+
+// partManifestSuffix is the suffix of the part manifest name, appended to the destination
+const partManifestSuffix = ".parts.json"
+
+// PartManifest describes a file split into parts, the order of the parts being the order of the content
+// Part names are relative to the folder of the manifest
+type PartManifest struct {
+	Name   string `json:"name"`
+	Parts  []Part `json:"parts"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// Part represents a part of a split file
+type Part struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size"`
+}
+
+// partName returns the name of the i-th part out of n, numbered from 1 with enough digits to keep them sorted
+func partName(name string, i, n int) string {
+	w := len(strconv.Itoa(n))
+	if w < 3 {
+		w = 3
+	}
+	return fmt.Sprintf("%s.part%0*d", name, w, i+1)
+}
+
+// UploadSplit uploads a file as numbered parts of at most partSize bytes, named after dst (dst.part001,
+// dst.part002, etc.), followed by a manifest describing their order and checksums, named dst.parts.json
+// The manifest is uploaded last so that its presence means all parts are there
+// The manifest describes the parts as they are in the source, so WithGzip, WithReadTransform, WithWriteTransform and
+// WithEncryption, which change their names, sizes and checksums, fail with ErrUnsupported
+func (f *FTP) UploadSplit(ctx context.Context, src, dst string, partSize int64, opts ...TransferOption) (m PartManifest, err error) {
+	// Log
+	l := fmt.Sprintf("FTP split upload of %s to %s", src, dst)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	if partSize <= 0 {
+		return m, fmt.Errorf("ftp: invalid part size %d", partSize)
+	}
+	if newTransferOptions(opts).encoded() {
+		return m, ErrUnsupported
+	}
+
+	// Open source
+	var srcFile *os.File
+	if srcFile, err = os.Open(src); err != nil {
+		return
+	}
+	defer srcFile.Close()
+	var fi os.FileInfo
+	if fi, err = srcFile.Stat(); err != nil {
+		return
+	}
+
+	// Build manifest
+	m = PartManifest{Name: path.Base(dst), Size: fi.Size()}
+	n := int((fi.Size() + partSize - 1) / partSize)
+	if n == 0 {
+		n = 1
+	}
+	h := sha256.New()
+	for i := 0; i < n; i++ {
+		p := Part{Name: partName(m.Name, i, n), Size: partSize}
+		if i == n-1 {
+			p.Size = fi.Size() - int64(i)*partSize
+		}
+		ph := sha256.New()
+		if _, err = copyContext(ctx, io.MultiWriter(h, ph), io.NewSectionReader(srcFile, int64(i)*partSize, p.Size), newTransferOptions(nil)); err != nil {
+			return
+		}
+		p.SHA256 = hex.EncodeToString(ph.Sum(nil))
+		m.Parts = append(m.Parts, p)
+	}
+	m.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	// Upload parts
+	dir := path.Dir(dst)
+	var offset int64
+	for _, p := range m.Parts {
+		log.Debugf("Uploading part %s", p.Name)
+		if err = f.UploadReader(ctx, io.NewSectionReader(srcFile, offset, p.Size), path.Join(dir, p.Name), opts...); err != nil {
+			return
+		}
+		offset += p.Size
+	}
+
+	// Upload manifest
+	var b []byte
+	if b, err = json.MarshalIndent(m, "", "  "); err != nil {
+		return
+	}
+	err = f.UploadReader(ctx, bytes.NewReader(b), dst+partManifestSuffix)
+	return
+}
+
+// ReadPartManifest downloads and parses a part manifest
+func (f *FTP) ReadPartManifest(ctx context.Context, p string) (m PartManifest, err error) {
+	var r io.ReadCloser
+	if r, err = f.DownloadPipe(ctx, p); err != nil {
+		return
+	}
+	defer r.Close()
+	err = json.NewDecoder(r).Decode(&m)
+	return
+}
+
+// PartsFromPattern builds a part manifest from the files of a folder matching a pattern, as understood by path.Match,
+// sorted by name. The manifest has no checksums, so parts are only checked against their listed size
+func (f *FTP) PartsFromPattern(ctx context.Context, dir, pattern string) (m PartManifest, err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// List
+	var entries map[string]*ftp.Entry
+	if entries, err = f.listFiles(conn, dir); err != nil {
+		return
+	}
+	for name, e := range entries {
+		var ok bool
+		if ok, err = path.Match(pattern, name); err != nil {
+			return
+		} else if ok {
+			m.Parts = append(m.Parts, Part{Name: name, Size: int64(e.Size)})
+			m.Size += int64(e.Size)
+		}
+	}
+	if len(m.Parts) == 0 {
+		return m, ErrNotFound
+	}
+	sort.Slice(m.Parts, func(i, j int) bool { return m.Parts[i].Name < m.Parts[j].Name })
+	return
+}
+
+// DownloadParts downloads the parts of a manifest, located in dir, in order and concatenates them into dst
+// Sizes and checksums of the parts and of the whole file are verified when the manifest has them, and dst is removed
+// if anything fails
+func (f *FTP) DownloadParts(ctx context.Context, dir string, m PartManifest, dst string, opts ...TransferOption) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP download of %d parts from %s to %s", len(m.Parts), dir, dst)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Create the destination file
+	var dstFile *os.File
+	if dstFile, err = os.Create(dst); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := dstFile.Close(); err == nil {
+			err = errClose
+		}
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+
+	o := newTransferOptions(opts)
+	h := sha256.New()
+	for _, p := range m.Parts {
+		// Check context error
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// Download part
+		log.Debugf("Downloading part %s", p.Name)
+		if err = f.downloadPart(ctx, conn, path.Join(dir, p.Name), p, io.MultiWriter(dstFile, h), o); err != nil {
+			return
+		}
+	}
+	if m.SHA256 != "" && hex.EncodeToString(h.Sum(nil)) != m.SHA256 {
+		return ErrChecksumMismatch
+	}
+	return
+}
+
+func (f *FTP) downloadPart(ctx context.Context, conn ServerConnexion, src string, p Part, w io.Writer, o transferOptions) (err error) {
+	var r io.ReadCloser
+	if r, err = f.retrFrom(conn, src, 0); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	h := sha256.New()
+	var n int64
+	if n, err = copyContext(ctx, io.MultiWriter(w, h), r, o); err != nil {
+		return
+	}
+
+	// Transforms and encryption change the size of the content
+	if n != p.Size && len(o.transforms) == 0 && o.encryption == nil {
+		return fmt.Errorf("ftp: part %s has %d bytes, expected %d", src, n, p.Size)
+	}
+	if p.SHA256 != "" && hex.EncodeToString(h.Sum(nil)) != p.SHA256 {
+		return ErrChecksumMismatch
+	}
+	return
+}
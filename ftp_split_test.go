@@ -0,0 +1,148 @@
+package ftp_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_UploadSplit(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "movie.mp4")
+	ioutil.WriteFile(src, []byte("0123456789"), 0644)
+
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	uploaded := make(map[string]string)
+	oConnexion.On("Stor", mock.Anything, mock.Anything).Return(func(path string, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		uploaded[path] = string(b)
+		return err
+	})
+	oFtp := NewFtp(oConnexion)
+
+	m, err := oFtp.UploadSplit(context.Background(), src, "/drop/movie.mp4", 4)
+	if err != nil {
+		t.Fatalf("base.UploadSplit() error = %v", err)
+	}
+	if len(m.Parts) != 3 || m.Size != 10 || m.Name != "movie.mp4" {
+		t.Fatalf("manifest = %+v", m)
+	}
+	for path, content := range map[string]string{
+		"/drop/movie.mp4.part001": "0123",
+		"/drop/movie.mp4.part002": "4567",
+		"/drop/movie.mp4.part003": "89",
+	} {
+		if uploaded[path] != content {
+			t.Errorf("%s = %q, want %q", path, uploaded[path], content)
+		}
+	}
+
+	// Manifest
+	var got ftp.PartManifest
+	if err = json.Unmarshal([]byte(uploaded["/drop/movie.mp4.parts.json"]), &got); err != nil {
+		t.Fatalf("manifest can't be parsed: %v", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("uploaded manifest = %+v, want %+v", got, m)
+	}
+
+	// Compressed parts wouldn't match the manifest
+	uploaded = make(map[string]string)
+	if _, err = oFtp.UploadSplit(context.Background(), src, "/drop/movie.mp4", 4, ftp.WithGzip()); err != ftp.ErrUnsupported {
+		t.Errorf("base.UploadSplit() with gzip error = %v, want %v", err, ftp.ErrUnsupported)
+	}
+	if len(uploaded) > 0 {
+		t.Errorf("uploaded %v, want nothing", uploaded)
+	}
+}
+
+func TestFTP_PartsFromPattern(t *testing.T) {
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/drop").Return([]*base.Entry{
+		{Name: "movie.mp4.part002", Size: 4, Type: base.EntryTypeFile},
+		{Name: "movie.mp4.part001", Size: 4, Type: base.EntryTypeFile},
+		{Name: "movie.mp4.parts.json", Size: 100, Type: base.EntryTypeFile},
+		{Name: "movie.mp4.part003", Size: 2, Type: base.EntryTypeFile},
+	}, nil)
+	oFtp := NewFtp(oConnexion)
+
+	m, err := oFtp.PartsFromPattern(context.Background(), "/drop", "movie.mp4.part[0-9]*")
+	if err != nil {
+		t.Fatalf("base.PartsFromPattern() error = %v", err)
+	}
+	if want := (ftp.PartManifest{Parts: []ftp.Part{
+		{Name: "movie.mp4.part001", Size: 4},
+		{Name: "movie.mp4.part002", Size: 4},
+		{Name: "movie.mp4.part003", Size: 2},
+	}, Size: 10}); !reflect.DeepEqual(m, want) {
+		t.Errorf("base.PartsFromPattern() = %+v, want %+v", m, want)
+	}
+	if _, err = oFtp.PartsFromPattern(context.Background(), "/drop", "other.*"); err != ftp.ErrNotFound {
+		t.Errorf("base.PartsFromPattern() error = %v, want %v", err, ftp.ErrNotFound)
+	}
+}
+
+func TestFTP_DownloadParts(t *testing.T) {
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+	m := ftp.PartManifest{
+		Name: "movie.mp4",
+		Parts: []ftp.Part{
+			{Name: "movie.mp4.part001", SHA256: sum("0123"), Size: 4},
+			{Name: "movie.mp4.part002", SHA256: sum("4567"), Size: 4},
+			{Name: "movie.mp4.part003", SHA256: sum("89"), Size: 2},
+		},
+		SHA256: sum("0123456789"),
+		Size:   10,
+	}
+	s := newFakeServer(t, map[string]string{
+		"/drop/movie.mp4.part001": "0123",
+		"/drop/movie.mp4.part002": "4567",
+		"/drop/movie.mp4.part003": "89",
+	})
+	oFtp := s.ftp(ftp.Configuration{})
+	dst := filepath.Join(t.TempDir(), "movie.mp4")
+
+	// Parts are concatenated in order
+	if err := oFtp.DownloadParts(context.Background(), "/drop", m, dst); err != nil {
+		t.Fatalf("base.DownloadParts() error = %v", err)
+	}
+	if b, _ := ioutil.ReadFile(dst); string(b) != "0123456789" {
+		t.Errorf("downloaded content = %q, want %q", b, "0123456789")
+	}
+
+	// A part that doesn't match its checksum
+	s.put("/drop/movie.mp4.part002", "4568")
+	if err := oFtp.DownloadParts(context.Background(), "/drop", m, dst); err != ftp.ErrChecksumMismatch {
+		t.Errorf("base.DownloadParts() with a corrupted part error = %v, want %v", err, ftp.ErrChecksumMismatch)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("destination should be removed, stat error = %v", err)
+	}
+
+	// A part cut short
+	s.put("/drop/movie.mp4.part002", "4567")
+	s.drop("/drop/movie.mp4.part002", 2)
+	if err := oFtp.DownloadParts(context.Background(), "/drop", m, dst); err == nil {
+		t.Error("base.DownloadParts() with a short part should fail")
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("destination should be removed, stat error = %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package ftp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// ManifestDiff represents the changes between two manifests
+type ManifestDiff struct {
+	Changed []ManifestFile `json:"changed,omitempty"`
+	Removed []ManifestFile `json:"removed,omitempty"`
+}
+
+// DiffManifests returns the files of cur that are new or differ from prev, by size or checksum, and the files of prev
+// that are not in cur
+func DiffManifests(prev, cur Manifest) (d ManifestDiff) {
+	files := make(map[string]ManifestFile, len(prev.Files))
+	for _, mf := range prev.Files {
+		files[mf.Path] = mf
+	}
+	for _, mf := range cur.Files {
+		p, ok := files[mf.Path]
+		delete(files, mf.Path)
+		if ok && p.Size == mf.Size && p.SHA256 == mf.SHA256 {
+			continue
+		}
+		d.Changed = append(d.Changed, mf)
+	}
+	for _, mf := range files {
+		d.Removed = append(d.Removed, mf)
+	}
+	sort.Slice(d.Removed, func(i, j int) bool { return d.Removed[i].Path < d.Removed[j].Path })
+	return
+}
+
+// LoadManifest reads a manifest saved with SaveManifest, a missing file being an empty manifest
+func LoadManifest(p string) (m Manifest, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(p); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+		}
+		return
+	}
+	err = json.Unmarshal(b, &m)
+	return
+}
+
+// SaveManifest writes a manifest to a temporary file renamed to p, so that p is never left half written
+func SaveManifest(p string, m Manifest) (err error) {
+	var b []byte
+	if b, err = json.Marshal(m); err != nil {
+		return
+	}
+	tmp := p + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return
+	}
+	return os.Rename(tmp, p)
+}
+
+// SyncDelta mirrors a local tree to a remote root without listing the remote tree: the manifest of the local tree is
+// compared to the manifest of the previous run, saved at statePath, and only new and modified files are uploaded and
+// removed files deleted. The remote tree is expected to only be modified by SyncDelta
+// The manifest is saved with what's been synced even when an error occurs, so that the next run picks up from there
+func (f *FTP) SyncDelta(ctx context.Context, localRoot, remoteRoot, statePath string, opts ...TransferOption) (d ManifestDiff, err error) {
+	// Log
+	l := fmt.Sprintf("FTP delta sync of %s to %s", localRoot, remoteRoot)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Manifests
+	var prev, cur Manifest
+	if prev, err = LoadManifest(statePath); err != nil {
+		return
+	}
+	if cur, err = LocalManifest(ctx, localRoot, true); err != nil {
+		return
+	}
+	d = DiffManifests(prev, cur)
+	log.Debugf("%d files to upload and %d files to delete", len(d.Changed), len(d.Removed))
+
+	// Save what's been synced
+	synced := make(map[string]ManifestFile, len(prev.Files))
+	for _, mf := range prev.Files {
+		synced[mf.Path] = mf
+	}
+	defer func() {
+		var m Manifest
+		for _, mf := range synced {
+			m.Files = append(m.Files, mf)
+		}
+		sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Path < m.Files[j].Path })
+		if errSave := SaveManifest(statePath, m); errSave != nil && err == nil {
+			err = errSave
+		}
+	}()
+
+	// Upload
+	dirs := make(map[string]bool)
+	for _, mf := range d.Changed {
+		dst := path.Join(remoteRoot, mf.Path)
+		if dir := path.Dir(dst); !dirs[dir] {
+			if err = f.makeDirs(ctx, dir); err != nil {
+				return
+			}
+			dirs[dir] = true
+		}
+		if err = f.Upload(ctx, filepath.Join(localRoot, filepath.FromSlash(mf.Path)), dst, opts...); err != nil {
+			return
+		}
+		synced[mf.Path] = mf
+	}
+
+	// Delete
+	for _, mf := range d.Removed {
+		if err = f.Remove(path.Join(remoteRoot, mf.Path)); err != nil && !isFileUnavailable(err) {
+			return
+		}
+		err = nil
+		delete(synced, mf.Path)
+	}
+	return
+}
+
+// makeDirs creates the missing folders of a path
+func (f *FTP) makeDirs(ctx context.Context, dir string) (err error) {
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+	f.checkFolders(conn, dir)
+	return
+}
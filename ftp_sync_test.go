@@ -0,0 +1,65 @@
+package ftp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_SyncDelta(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "local")
+	state := filepath.Join(dir, "state.json")
+	os.MkdirAll(filepath.Join(local, "sub"), 0755)
+	ioutil.WriteFile(filepath.Join(local, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(local, "b.txt"), []byte("b"), 0644)
+	ioutil.WriteFile(filepath.Join(local, "sub", "c.txt"), []byte("c"), 0644)
+
+	run := func() (stored, deleted []string) {
+		oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+		oConnexion.On("FileSize", "/remote").Return(int64(0), nil)
+		oConnexion.On("FileSize", "/remote/sub").Return(int64(0), errors.New("not found"))
+		oConnexion.On("MakeDir", "/remote/sub").Return(nil)
+		oConnexion.On("Stor", mock.Anything, mock.Anything).Return(func(p string, r io.Reader) error {
+			stored = append(stored, p)
+			return nil
+		})
+		oConnexion.On("Delete", mock.Anything).Return(func(p string) error {
+			deleted = append(deleted, p)
+			return nil
+		})
+		if _, err := NewFtp(oConnexion).SyncDelta(context.Background(), local, "/remote", state); err != nil {
+			t.Fatalf("FTP.SyncDelta() error = %v", err)
+		}
+		sort.Strings(stored)
+		return
+	}
+
+	// Everything is uploaded the first time
+	if stored, _ := run(); !reflect.DeepEqual(stored, []string{"/remote/a.txt", "/remote/b.txt", "/remote/sub/c.txt"}) {
+		t.Errorf("first sync uploaded %v", stored)
+	}
+
+	// Only changes are synced next
+	ioutil.WriteFile(filepath.Join(local, "a.txt"), []byte("A"), 0644)
+	os.Remove(filepath.Join(local, "sub", "c.txt"))
+	stored, deleted := run()
+	if !reflect.DeepEqual(stored, []string{"/remote/a.txt"}) {
+		t.Errorf("second sync uploaded %v, want [/remote/a.txt]", stored)
+	}
+	if !reflect.DeepEqual(deleted, []string{"/remote/sub/c.txt"}) {
+		t.Errorf("second sync deleted %v, want [/remote/sub/c.txt]", deleted)
+	}
+	if stored, deleted = run(); len(stored) > 0 || len(deleted) > 0 {
+		t.Errorf("third sync uploaded %v and deleted %v, want nothing", stored, deleted)
+	}
+}
@@ -0,0 +1,90 @@
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// Tail follows a growing remote file and writes its content to w until the context is cancelled
+// Every poll interval, the file size is checked and only the new bytes are downloaded. If the file shrinks, it is
+// considered rotated and followed from its beginning again
+func (f *FTP) Tail(ctx context.Context, src string, w io.Writer, poll time.Duration) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP tail of %s every %s", src, poll)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	var offset int64
+	for {
+		// Check context error
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// Fetch new bytes
+		var n int64
+		if n, err = f.tail(ctx, src, w, offset); err != nil {
+			// Bytes written before the error must not be written again
+			if n > 0 {
+				offset += n
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Errorf("[FTP] error while tailing %s : %s", src, err.Error())
+		} else if n < 0 {
+			log.Debugf("%s has shrunk, following it from its beginning", src)
+			offset = 0
+			continue
+		} else {
+			offset += n
+		}
+
+		// Wait
+		select {
+		case <-time.After(poll):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// tail writes the bytes of src located after offset to w and returns how many were written, or -1 if the
+// file is now smaller than offset
+func (f *FTP) tail(ctx context.Context, src string, w io.Writer, offset int64) (n int64, err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// File size
+	var s int64
+	if s, err = f.fileSize(conn, src); err != nil {
+		return
+	}
+	if s < offset {
+		return -1, nil
+	} else if s == offset {
+		return 0, nil
+	}
+
+	// Download new bytes
+	var r io.ReadCloser
+	if r, err = f.retrFrom(conn, src, offset); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); errClose != nil && err == nil {
+			err = errClose
+		}
+	}()
+	return copyContext(ctx, w, r, newTransferOptions(nil))
+}
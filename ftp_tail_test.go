@@ -0,0 +1,68 @@
+package ftp_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+// tailWriter records what's written, and fails the first write of more than a byte after writing half of it
+type tailWriter struct {
+	buf    bytes.Buffer
+	fail   bool
+	failed bool
+	m      sync.Mutex
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.fail && !w.failed && len(p) > 1 {
+		w.failed = true
+		w.buf.Write(p[:len(p)/2])
+		return len(p) / 2, errors.New("write failed")
+	}
+	return w.buf.Write(p)
+}
+
+func (w *tailWriter) String() string {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.buf.String()
+}
+
+// waitFor waits for fn to be true
+func waitFor(t *testing.T, fn func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !fn(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestFTP_Tail(t *testing.T) {
+	for _, fail := range []bool{false, true} {
+		s := newFakeServer(t, map[string]string{"/logs/app.log": "0123"})
+		w := &tailWriter{fail: fail}
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- s.ftp(ftp.Configuration{}).Tail(ctx, "/logs/app.log", w, 10*time.Millisecond) }()
+
+		// New bytes are written once, even after a failed copy
+		waitFor(t, func() bool { return w.String() == "0123" })
+		s.put("/logs/app.log", "0123456")
+		waitFor(t, func() bool { return len(w.String()) >= 7 })
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("fail %v: base.Tail() error = %v, want %v", fail, err, context.Canceled)
+		}
+		if w.String() != "0123456" {
+			t.Errorf("fail %v: tailed %q, want %q", fail, w.String(), "0123456")
+		}
+	}
+}
@@ -1,7 +1,16 @@
 package ftp_test
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/textproto"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -206,6 +215,587 @@ func TestFTP_List(t *testing.T) {
 	}
 }
 
+func TestFTP_ListMany(t *testing.T) {
+	aFiles := getListOfFiles()
+	oFtp := NewFtp(getMockOfServerConnexion(aFiles))
+
+	aFolders := []string{"tenant-a", "tenant-b", "tenant-c"}
+	got, err := oFtp.ListMany(context.Background(), aFolders, 2)
+	if err != nil {
+		t.Fatalf("base.ListMany() error = %v", err)
+	}
+	if len(got) != len(aFolders) {
+		t.Fatalf("base.ListMany() returned %d folders, want %d", len(got), len(aFolders))
+	}
+	for _, sFolder := range aFolders {
+		if !reflect.DeepEqual(got[sFolder], aFiles[:7]) {
+			t.Errorf("base.ListMany()[%s] = %v, want %v", sFolder, got[sFolder], aFiles[:7])
+		}
+	}
+
+	// A folder that can't be listed
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "tenant-b").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such directory"})
+	oConnexion.On("List", mock.Anything).Return(aFiles, nil)
+	got, err = NewFtp(oConnexion).ListMany(context.Background(), aFolders, 2)
+	var e *textproto.Error
+	if !errors.As(err, &e) || e.Code != base.StatusFileUnavailable {
+		t.Errorf("base.ListMany() error = %v, want the listing error", err)
+	}
+	if _, ok := got["tenant-b"]; ok || len(got) != 2 {
+		t.Errorf("base.ListMany() returned %d folders, want the 2 that could be listed", len(got))
+	}
+
+	// Canceled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err = oFtp.ListMany(ctx, aFolders, 2); err != context.Canceled || len(got) != 0 {
+		t.Errorf("base.ListMany() = %d folders, %v, want none, %v", len(got), err, context.Canceled)
+	}
+}
+
+func TestFTP_Touch(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "drop/file.xml.done", mock.MatchedBy(func(r io.Reader) bool {
+		b, err := io.ReadAll(r)
+		return err == nil && len(b) == 0
+	})).Return(nil)
+	oFtp := NewFtp(oConnexion)
+
+	if err := oFtp.Touch("drop/file.xml.done"); err != nil {
+		t.Fatalf("base.Touch() error = %v", err)
+	}
+	oConnexion.AssertCalled(t, "Stor", "drop/file.xml.done", mock.Anything)
+}
+
+func TestFTP_UploadReader_Retry(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "dst.xml", mock.Anything).Return(&textproto.Error{Code: base.StatusTransfertAborted, Msg: "Connection closed; transfer aborted"}).Once()
+	oConnexion.On("FileSize", "dst.xml").Return(int64(4), nil)
+	var got string
+	oConnexion.On("StorFrom", "dst.xml", mock.Anything, uint64(4)).Return(func(path string, r io.Reader, offset uint64) error {
+		b, err := io.ReadAll(r)
+		got = string(b)
+		return err
+	})
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{Retry: ftp.RetryPolicy{Attempts: 2}}, oDialer)
+
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("0123456789"), "dst.xml"); err != nil {
+		t.Fatalf("base.UploadReader() error = %v", err)
+	}
+	if got != "456789" {
+		t.Errorf("resumed upload sent %q, want %q", got, "456789")
+	}
+}
+
+func TestFTP_MaxUploadSize(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "dst.xml", mock.Anything).Return(func(path string, r io.Reader) error {
+		_, err := io.ReadAll(r)
+		return err
+	})
+	oConnexion.On("Delete", "dst.xml").Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{MaxUploadSize: 4, Retry: ftp.RetryPolicy{Attempts: 2}}, oDialer)
+
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("0123456789"), "dst.xml"); err != ftp.ErrFileTooLarge {
+		t.Fatalf("base.UploadReader() error = %v, want %v", err, ftp.ErrFileTooLarge)
+	}
+	oConnexion.AssertNumberOfCalls(t, "Stor", 1)
+	oConnexion.AssertCalled(t, "Delete", "dst.xml")
+}
+
+func TestFTP_DownloadPipe_Error(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Retr", "missing.mp4").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such file"})
+	oFtp := NewFtp(oConnexion)
+
+	if _, err := oFtp.DownloadPipe(context.Background(), "missing.mp4"); err == nil {
+		t.Fatal("base.DownloadPipe() should fail when the transfer can't be opened")
+	}
+	oConnexion.AssertCalled(t, "Quit")
+}
+
+func TestFTP_UploadReader_Gzip(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	var got string
+	oConnexion.On("Stor", "feed.xml.gz", mock.Anything).Return(func(path string, r io.Reader) error {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		b, err := io.ReadAll(zr)
+		got = string(b)
+		return err
+	})
+	oFtp := NewFtp(oConnexion)
+
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("<feed/>"), "feed.xml", ftp.WithGzip()); err != nil {
+		t.Fatalf("base.UploadReader() error = %v", err)
+	}
+	if got != "<feed/>" {
+		t.Errorf("uploaded content = %q, want %q", got, "<feed/>")
+	}
+}
+
+func TestFTP_Upload_HashDedup(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "catalog.xml")
+	ioutil.WriteFile(src, []byte("<catalog/>"), 0644)
+
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/drop").Return([]*base.Entry{{Name: "catalog.xml", Size: 10, Type: base.EntryTypeFile}}, nil)
+	oConnexion.On("Retr", "/drop/catalog.xml.sha256").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such file"})
+	uploaded := make(map[string]string)
+	oConnexion.On("Stor", mock.Anything, mock.Anything).Return(func(path string, r io.Reader) error {
+		b, err := io.ReadAll(r)
+		uploaded[path] = string(b)
+		return err
+	})
+	oFtp := NewFtp(oConnexion)
+
+	if err := oFtp.Upload(context.Background(), src, "/drop/catalog.xml", ftp.WithHashDedup()); err != nil {
+		t.Fatalf("base.Upload() error = %v", err)
+	}
+	if uploaded["/drop/catalog.xml"] != "<catalog/>" {
+		t.Errorf("uploaded content = %q", uploaded["/drop/catalog.xml"])
+	}
+	if want := "fa0f34696a84232f54ea3fd9a1e720813844d265782b42968b748b6743cec783  catalog.xml\n"; uploaded["/drop/catalog.xml.sha256"] != want {
+		t.Errorf("sidecar = %q, want %q", uploaded["/drop/catalog.xml.sha256"], want)
+	}
+
+	// The compressed file can't be compared with the source
+	uploaded = make(map[string]string)
+	if err := oFtp.Upload(context.Background(), src, "/drop/catalog.xml", ftp.WithHashDedup(), ftp.WithGzip()); err != ftp.ErrUnsupported {
+		t.Errorf("base.Upload() with gzip error = %v, want %v", err, ftp.ErrUnsupported)
+	}
+	if len(uploaded) > 0 {
+		t.Errorf("uploaded %v, want nothing", uploaded)
+	}
+}
+
+func TestFTP_ResumeUpload(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "movie.mp4")
+	ioutil.WriteFile(src, []byte("0123456789"), 0644)
+
+	for _, tt := range []struct {
+		name     string
+		features map[string]string
+		size     int64
+		want     string
+	}{
+		{name: "REST STREAM", features: map[string]string{"REST": "STREAM"}, size: 6, want: "6789"},
+		{name: "no REST STREAM", features: map[string]string{"SIZE": ""}, size: 6, want: "0123456789"},
+		{name: "larger remote", features: map[string]string{"REST": "STREAM"}, size: 20, want: "0123456789"},
+	} {
+		oConnexion := &mocks.ServerConnexion{}
+		oConnexion.On("Login", "", "").Return(nil)
+		oConnexion.On("Features").Return(tt.features)
+		oConnexion.On("Quit").Return(nil)
+		oConnexion.On("FileSize", "movie.mp4").Return(tt.size, nil)
+		var got string
+		read := func(r io.Reader) error {
+			b, err := io.ReadAll(r)
+			got = string(b)
+			return err
+		}
+		oConnexion.On("Stor", "movie.mp4", mock.Anything).Return(func(path string, r io.Reader) error { return read(r) })
+		oConnexion.On("StorFrom", "movie.mp4", mock.Anything, uint64(tt.size)).Return(func(path string, r io.Reader, offset uint64) error { return read(r) })
+		oFtp := NewFtp(oConnexion)
+
+		if err := oFtp.ResumeUpload(context.Background(), src, "movie.mp4"); err != nil {
+			t.Fatalf("%s: base.ResumeUpload() error = %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: sent %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// Offsets are lost once the content is compressed
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	if err := NewFtp(oConnexion).ResumeUpload(context.Background(), src, "movie.mp4", ftp.WithGzip()); err != ftp.ErrUnsupported {
+		t.Errorf("base.ResumeUpload() with gzip error = %v, want %v", err, ftp.ErrUnsupported)
+	}
+	oConnexion.AssertNotCalled(t, "Stor", mock.Anything, mock.Anything)
+}
+
+func TestFTP_UploadReader_NoRetry(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "dst.xml", mock.Anything).Return(&textproto.Error{Code: base.StatusTransfertAborted, Msg: "Connection closed; transfer aborted"})
+	oConnexion.On("FileSize", "dst.xml").Return(int64(0), nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{Retry: ftp.RetryPolicy{Attempts: 2}}, oDialer)
+
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("0123456789"), "dst.xml", ftp.WithNoRetry()); err == nil {
+		t.Fatal("base.UploadReader() should fail")
+	}
+	oConnexion.AssertNumberOfCalls(t, "Stor", 1)
+
+	// Per call policy
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("0123456789"), "dst.xml", ftp.WithRetryPolicy(ftp.RetryPolicy{Attempts: 3})); err == nil {
+		t.Fatal("base.UploadReader() should fail")
+	}
+	oConnexion.AssertNumberOfCalls(t, "Stor", 1+3)
+}
+
+func TestFTP_ExistsDir(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("CurrentDir").Return("/home", nil)
+	oConnexion.On("ChangeDir", "drop").Return(nil)
+	oConnexion.On("ChangeDir", "missing").Return(&textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such directory"})
+	oConnexion.On("ChangeDir", "/home").Return(nil)
+	oFtp := NewFtp(oConnexion)
+
+	if b, err := oFtp.ExistsDir("drop"); err != nil || !b {
+		t.Errorf("base.ExistsDir(drop) = %v, %v, want true, nil", b, err)
+	}
+	oConnexion.AssertCalled(t, "ChangeDir", "/home")
+	if b, err := oFtp.ExistsDir("missing"); err != nil || b {
+		t.Errorf("base.ExistsDir(missing) = %v, %v, want false, nil", b, err)
+	}
+}
+
+func TestFTP_Exists_Fallback(t *testing.T) {
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", mock.Anything, mock.Anything).Return(nil)
+	oConnexion.On("Features").Return(map[string]string{})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("FileSize", mock.Anything).Return(int64(0), &textproto.Error{Code: base.StatusNotImplemented, Msg: "SIZE not implemented"})
+	oConnexion.On("List", "folder").Return(getListOfFiles(), nil)
+	oFtp := NewFtp(oConnexion)
+
+	if b, err := oFtp.Exists("folder/ab-test.json"); err != nil || !b {
+		t.Errorf("base.Exists(folder/ab-test.json) = %v, %v, want true, nil", b, err)
+	}
+	if b, err := oFtp.Exists("folder/missing.json"); err != nil || b {
+		t.Errorf("base.Exists(folder/missing.json) = %v, %v, want false, nil", b, err)
+	}
+}
+
+func TestFTP_CwdBeforeTransfer(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("CurrentDir").Return("/home", nil)
+	oConnexion.On("ChangeDir", "drop/in").Return(nil)
+	oConnexion.On("Stor", "file.xml.done", mock.Anything).Return(nil)
+	oConnexion.On("ChangeDir", "/home").Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{CwdBeforeTransfer: true}, oDialer)
+
+	if err := oFtp.Touch("drop/in/file.xml.done"); err != nil {
+		t.Fatalf("base.Touch() error = %v", err)
+	}
+	oConnexion.AssertCalled(t, "ChangeDir", "drop/in")
+	oConnexion.AssertCalled(t, "Stor", "file.xml.done", mock.Anything)
+	oConnexion.AssertCalled(t, "ChangeDir", "/home")
+}
+
+func TestFTP_OnSlowOperation(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("FileSize", "slow.mp4").Return(func(string) int64 {
+		time.Sleep(20 * time.Millisecond)
+		return 1000
+	}, nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{SlowThresholds: map[string]time.Duration{ftp.OpFileSize: 10 * time.Millisecond}}, oDialer)
+	var ops []string
+	oFtp.OnSlowOperation = func(op, path string, elapsed time.Duration) {
+		ops = append(ops, op+" "+path)
+	}
+
+	if _, err := oFtp.FileSize("slow.mp4"); err != nil {
+		t.Fatalf("base.FileSize() error = %v", err)
+	}
+	if want := []string{"file_size slow.mp4"}; !reflect.DeepEqual(ops, want) {
+		t.Errorf("slow operations = %v, want %v", ops, want)
+	}
+}
+
+func TestFTP_FallbackCredentials(t *testing.T) {
+	oRejected := &mocks.ServerConnexion{}
+	oRejected.On("Login", "user", "old").Return(&textproto.Error{Code: 530, Msg: "Login incorrect."})
+	oRejected.On("Quit").Return(nil)
+	oAccepted := &mocks.ServerConnexion{}
+	oAccepted.On("Login", "user", "new").Return(nil)
+	oAccepted.On("Features").Return(map[string]string{})
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oRejected, nil).Once()
+	oDialer.On("Dial", mock.Anything).Return(oAccepted, nil).Twice()
+	oFtp := ftp.New(ftp.Configuration{
+		FallbackCredentials: []ftp.Credentials{{Password: "new", Username: "user"}},
+		Password:            "old",
+		Username:            "user",
+	}, oDialer)
+
+	conn, err := oFtp.Connect()
+	if err != nil {
+		t.Fatalf("base.Connect() error = %v", err)
+	}
+	if conn != oAccepted {
+		t.Error("base.Connect() didn't return the connection logged in with the fallback credentials")
+	}
+	oRejected.AssertCalled(t, "Quit")
+
+	// The credentials that worked are tried first from then on
+	if conn, err = oFtp.Connect(); err != nil {
+		t.Fatalf("base.Connect() error = %v", err)
+	}
+	if conn != oAccepted {
+		t.Error("base.Connect() didn't log in with the last credentials that worked")
+	}
+	oRejected.AssertNumberOfCalls(t, "Login", 1)
+	oDialer.AssertNumberOfCalls(t, "Dial", 3)
+}
+
+// bannerConnexion is a connexion knowing the greeting of the server
+type bannerConnexion struct {
+	*mocks.ServerConnexion
+	banner string
+}
+
+func (c bannerConnexion) Banner() string {
+	return c.banner
+}
+
+func TestFTP_Banner(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(bannerConnexion{ServerConnexion: oConnexion, banner: "ProFTPD Server ready."}, nil)
+
+	// Matching banner
+	oFtp := ftp.New(ftp.Configuration{BannerPattern: "^ProFTPD "}, oDialer)
+	if _, err := oFtp.Connect(); err != nil {
+		t.Fatalf("base.Connect() error = %v", err)
+	}
+	if oFtp.Banner() != "ProFTPD Server ready." {
+		t.Errorf("base.Banner() = %q, want %q", oFtp.Banner(), "ProFTPD Server ready.")
+	}
+
+	// Unexpected banner, credentials are not sent
+	oConnexion.Calls = nil
+	oFtp = ftp.New(ftp.Configuration{BannerPattern: "^vsFTPd "}, oDialer)
+	if _, err := oFtp.Connect(); err != ftp.ErrUnexpectedBanner {
+		t.Errorf("base.Connect() error = %v, want %v", err, ftp.ErrUnexpectedBanner)
+	}
+	oConnexion.AssertNotCalled(t, "Login", mock.Anything, mock.Anything)
+	oConnexion.AssertCalled(t, "Quit")
+
+	// Invalid pattern
+	defer func() {
+		if recover() == nil {
+			t.Error("ftp.New() with an invalid banner pattern should panic")
+		}
+	}()
+	ftp.New(ftp.Configuration{BannerPattern: "("}, oDialer)
+}
+
+func TestFTP_FileSize_DisableSize(t *testing.T) {
+	oConnexion := &mocks.ServerConnexion{}
+	oConnexion.On("Login", "", "").Return(nil)
+	oConnexion.On("Features").Return(map[string]string{"SIZE": ""})
+	oConnexion.On("Quit").Return(nil)
+	oConnexion.On("List", "/in").Return([]*base.Entry{{Name: "file.mp4", Size: 42, Type: base.EntryTypeFile}}, nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	oFtp := ftp.New(ftp.Configuration{Quirks: ftp.QuirksMainframe}, oDialer)
+
+	s, err := oFtp.FileSize("/in/file.mp4")
+	if err != nil || s != 42 {
+		t.Fatalf("base.FileSize() = %d, %v, want 42, nil", s, err)
+	}
+
+	// Folders are checked by listing as well
+	oConnexion.On("List", "/").Return([]*base.Entry{{Name: "out", Type: base.EntryTypeFolder}}, nil)
+	oConnexion.On("Rename", "/in/file.mp4", "/out/file.mp4").Return(nil)
+	if err = oFtp.Rename("/in/file.mp4", "/out/file.mp4"); err != nil {
+		t.Fatalf("base.Rename() error = %v", err)
+	}
+	oConnexion.AssertNotCalled(t, "MakeDir", mock.Anything)
+	oConnexion.AssertNotCalled(t, "FileSize", mock.Anything)
+
+	// Unknown quirks
+	defer func() {
+		if recover() == nil {
+			t.Error("ftp.New() with unknown quirks should panic")
+		}
+	}()
+	ftp.New(ftp.Configuration{Quirks: "unknown"}, oDialer)
+}
+
+func TestFTP_AppendReader(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/logs/app.log": "line 1\n"})
+	oFtp := s.ftp(ftp.Configuration{})
+
+	// Existing file
+	if err := oFtp.AppendReader(context.Background(), strings.NewReader("line 2\n"), "/logs/app.log"); err != nil {
+		t.Fatalf("base.AppendReader() error = %v", err)
+	}
+	if c, _ := s.file("/logs/app.log"); c != "line 1\nline 2\n" {
+		t.Errorf("content = %q, want %q", c, "line 1\nline 2\n")
+	}
+
+	// New file
+	if err := oFtp.AppendReader(context.Background(), strings.NewReader("line 1\n"), "/logs/new.log"); err != nil {
+		t.Fatalf("base.AppendReader() error = %v", err)
+	}
+	if c, _ := s.file("/logs/new.log"); c != "line 1\n" {
+		t.Errorf("content = %q, want %q", c, "line 1\n")
+	}
+	if n, stor := s.count("APPE"), s.count("STOR"); n != 2 || stor != 0 {
+		t.Errorf("sent %d APPE and %d STOR, want 2 APPE only", n, stor)
+	}
+}
+
+func TestFTP_Download_Incomplete(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/in/movie.mp4": "0123456789"})
+	dst := filepath.Join(t.TempDir(), "movie.mp4")
+
+	// Short transfer
+	s.drop("/in/movie.mp4", 4)
+	oFtp := s.ftp(ftp.Configuration{})
+	defer oFtp.Close()
+	if err := oFtp.Download(context.Background(), "/in/movie.mp4", dst); err != ftp.ErrIncompleteTransfer {
+		t.Errorf("base.Download() error = %v, want %v", err, ftp.ErrIncompleteTransfer)
+	}
+	if _, err := os.Stat(dst); !os.IsNotExist(err) {
+		t.Errorf("partial file should be removed, stat error = %v", err)
+	}
+
+	// Too large, known beforehand or while copying
+	for _, disableSize := range []bool{false, true} {
+		oFtp := s.ftp(ftp.Configuration{DisableSize: disableSize, MaxDownloadSize: 4})
+		defer oFtp.Close()
+		if err := oFtp.Download(context.Background(), "/in/movie.mp4", dst); err != ftp.ErrFileTooLarge {
+			t.Errorf("base.Download() with DisableSize %v error = %v, want %v", disableSize, err, ftp.ErrFileTooLarge)
+		}
+		if _, err := os.Stat(dst); !os.IsNotExist(err) {
+			t.Errorf("partial file should be removed with DisableSize %v, stat error = %v", disableSize, err)
+		}
+	}
+}
+
+func TestFTP_MaxDownloadSize(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/in/movie.mp4": "0123456789"})
+	oFtp := s.ftp(ftp.Configuration{MaxDownloadSize: 4})
+	defer oFtp.Close()
+	ctx := context.Background()
+
+	for name, fn := range map[string]func() error{
+		"DownloadReader": func() error {
+			r, err := oFtp.DownloadReader("/in/movie.mp4")
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			_, err = ioutil.ReadAll(r)
+			return err
+		},
+		"DownloadPipe": func() error {
+			r, err := oFtp.DownloadPipe(ctx, "/in/movie.mp4")
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			_, err = ioutil.ReadAll(r)
+			return err
+		},
+		"DownloadZip": func() error {
+			return oFtp.DownloadZip(ctx, []string{"/in/movie.mp4"}, ioutil.Discard)
+		},
+		"DownloadParts": func() error {
+			m := ftp.PartManifest{Name: "movie.mp4", Parts: []ftp.Part{{Name: "movie.mp4", Size: 10}}, Size: 10}
+			return oFtp.DownloadParts(ctx, "/in", m, filepath.Join(t.TempDir(), "movie.mp4"))
+		},
+	} {
+		if err := fn(); !errors.Is(err, ftp.ErrFileTooLarge) {
+			t.Errorf("base.%s() error = %v, want %v", name, err, ftp.ErrFileTooLarge)
+		}
+	}
+}
+
+func TestFTP_DownloadReader(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/in/movie.mp4": "0123456789"})
+	oFtp := s.ftp(ftp.Configuration{PoolSize: 2})
+	defer oFtp.Close()
+
+	r, err := oFtp.DownloadReader("/in/movie.mp4")
+	if err != nil {
+		t.Fatalf("base.DownloadReader() error = %v", err)
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != "0123456789" {
+		t.Errorf("read %q, want %q", b, "0123456789")
+	}
+	if err = r.Close(); err != nil {
+		t.Fatalf("closing error = %v", err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatalf("closing again error = %v", err)
+	}
+
+	// The connection is back in the pool once, so only one of two sessions can reuse it
+	for i := 0; i < 2; i++ {
+		session, err := oFtp.AcquireSession(context.Background())
+		if err != nil {
+			t.Fatalf("base.AcquireSession() error = %v", err)
+		}
+		defer session.Release()
+	}
+	if n := s.count("USER"); n != 2 {
+		t.Errorf("%d logins, want 2", n)
+	}
+}
+
+func TestFTP_DownloadReader_Options(t *testing.T) {
+	s := newFakeServer(t, map[string]string{"/in/readme.txt": "content"})
+	oFtp := s.ftp(ftp.Configuration{})
+	defer oFtp.Close()
+	key := strings.Repeat("k", 32)
+	if err := oFtp.UploadReader(context.Background(), strings.NewReader("secret"), "/in/secret.txt", ftp.WithEncryption([]byte(key))); err != nil {
+		t.Fatalf("base.UploadReader() error = %v", err)
+	}
+
+	for _, v := range []struct {
+		opts []ftp.TransferOption
+		src  string
+		want string
+	}{
+		{opts: []ftp.TransferOption{ftp.WithEncryption([]byte(key))}, src: "/in/secret.txt", want: "secret"},
+		{opts: []ftp.TransferOption{ftp.WithReadTransform(func(r io.Reader) io.Reader {
+			b, _ := ioutil.ReadAll(r)
+			return strings.NewReader(strings.ToUpper(string(b)))
+		})}, src: "/in/readme.txt", want: "CONTENT"},
+	} {
+		var progress int64
+		r, err := oFtp.DownloadReader(v.src, append(v.opts, ftp.WithProgress(func(n int64) { progress = n }))...)
+		if err != nil {
+			t.Fatalf("base.DownloadReader(%s) error = %v", v.src, err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("reading %s error = %v", v.src, err)
+		}
+		if err = r.Close(); err != nil {
+			t.Fatalf("closing %s error = %v", v.src, err)
+		}
+		if string(b) != v.want {
+			t.Errorf("read %q from %s, want %q", b, v.src, v.want)
+		}
+		if progress == 0 {
+			t.Errorf("no progress reported for %s", v.src)
+		}
+	}
+}
+
 func prepareTestFTP_list() {
 
 }
@@ -219,6 +809,7 @@ func NewFtp(oConnexion ftp.ServerConnexion) *ftp.FTP {
 func getMockOfServerConnexion(aFiles []*base.Entry) ftp.ServerConnexion {
 	oConnexion := &mocks.ServerConnexion{}
 	oConnexion.On("Login", mock.Anything, mock.Anything).Return(nil)
+	oConnexion.On("Features").Return(map[string]string{"MDTM": "", "REST": "STREAM", "SIZE": ""})
 	oConnexion.On("Quit").Return(nil)
 	oConnexion.On("List", mock.Anything).Return(aFiles, nil)
 	return oConnexion
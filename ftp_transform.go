@@ -0,0 +1,83 @@
+package ftp
+
+import (
+	"io"
+	"io/ioutil"
+)
+
+// transform wraps the data stream of a transfer
+type transform func(r io.Reader) io.ReadCloser
+
+// pipeWriter returns a reader of what the writer returned by fn writes when given the content of r, which is copied
+// in a goroutine as the reader is read. Closing the reader stops the copy and waits for the goroutine to be done with
+// r, so that r can be reused once Close returns
+func pipeWriter(r io.Reader, fn func(w io.Writer) io.WriteCloser) io.ReadCloser {
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w := fn(pw)
+		_, err := io.Copy(w, r)
+		if errClose := w.Close(); err == nil {
+			err = errClose
+		}
+		pw.CloseWithError(err)
+	}()
+	return &pipeReader{PipeReader: pr, done: done}
+}
+
+// pipeReader is the reader of a pipeWriter
+type pipeReader struct {
+	*io.PipeReader
+	done chan struct{}
+}
+
+// Close implements the io.Closer interface
+func (r *pipeReader) Close() error {
+	err := r.PipeReader.Close()
+	<-r.done
+	return err
+}
+
+// transformedReader is a reader wrapped by transforms, which are all closed with it
+type transformedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func applyTransforms(r io.Reader, ts []transform) io.ReadCloser {
+	tr := &transformedReader{Reader: r}
+	for _, t := range ts {
+		rc := t(tr.Reader)
+		tr.Reader = rc
+		tr.closers = append(tr.closers, rc)
+	}
+	return tr
+}
+
+// Close implements the io.Closer interface
+func (r *transformedReader) Close() (err error) {
+	for i := len(r.closers) - 1; i >= 0; i-- {
+		if errClose := r.closers[i].Close(); err == nil {
+			err = errClose
+		}
+	}
+	return
+}
+
+// WithReadTransform wraps the data stream of a transfer with fn, which is given what's read: the source in uploads
+// and the remote content in downloads. Transforms are applied in order, after the compression of uploads
+func WithReadTransform(fn func(r io.Reader) io.Reader) TransferOption {
+	return func(o *transferOptions) {
+		o.transforms = append(o.transforms, func(r io.Reader) io.ReadCloser { return ioutil.NopCloser(fn(r)) })
+	}
+}
+
+// WithWriteTransform wraps the data stream of a transfer with the writer returned by fn, which is given where to
+// write. The writer is closed at the end of the stream so that it can flush what it holds. Transforms are applied in
+// order, after the compression of uploads
+func WithWriteTransform(fn func(w io.Writer) io.WriteCloser) TransferOption {
+	return func(o *transferOptions) {
+		o.transforms = append(o.transforms, func(r io.Reader) io.ReadCloser { return pipeWriter(r, fn) })
+	}
+}
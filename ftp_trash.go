@@ -0,0 +1,177 @@
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// trashLayout is the layout of the timestamp prefixing the names of trashed files, which sorts chronologically
+const trashLayout = "20060102T150405.000000000Z"
+
+// trashName returns the name of a file moved to the trash at t, which holds its escaped original path so that files
+// of different folders don't collide and can be found back
+func trashName(p string, t time.Time) string {
+	return t.UTC().Format(trashLayout) + "_" + url.PathEscape(strings.TrimPrefix(path.Clean(p), "/"))
+}
+
+// trashTime returns when a trashed file was moved to the trash
+func trashTime(name string) (time.Time, bool) {
+	i := strings.Index(name, "_")
+	if i < 0 {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(trashLayout, name[:i])
+	return t, err == nil
+}
+
+// remove deletes a file, or moves it to the trash folder under a timestamped name if there's one
+func (f *FTP) remove(conn ServerConnexion, src string) (err error) {
+	if f.TrashDir != "" {
+		f.checkFolders(conn, f.TrashDir)
+	}
+	return f.discard(conn, src)
+}
+
+// discard is remove once the trash folder has been checked
+func (f *FTP) discard(conn ServerConnexion, src string) (err error) {
+	defer f.invalidateList(src)
+	if f.TrashDir == "" {
+		log.Debugf("Removing %s", src)
+		return conn.Delete(src)
+	}
+	dst := path.Join(f.TrashDir, trashName(src, time.Now()))
+	defer f.invalidateList(dst)
+	log.Debugf("Moving %s to %s", src, dst)
+	return conn.Rename(src, dst)
+}
+
+// delete deletes a file without going through the trash, which is meant for the files the client manages itself such
+// as locks and heartbeat files
+func (f *FTP) delete(p string) (err error) {
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquire(); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// Delete
+	log.Debugf("Removing %s", p)
+	return conn.Delete(p)
+}
+
+// RemoveGlob removes the files of a folder whose name matches a pattern, as understood by path.Match, or moves them
+// to the trash folder if there's one, and returns how many were removed
+func (f *FTP) RemoveGlob(ctx context.Context, folder, pattern string) (n int, err error) {
+	// Log
+	l := fmt.Sprintf("FTP remove of %s in %s", pattern, folder)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+		f.checkSlow(OpRemove, folder, time.Since(now))
+	}(time.Now())
+
+	// Check pattern
+	if _, err = path.Match(pattern, ""); err != nil {
+		return
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// List
+	var names []string
+	if names, err = f.fileNames(conn, folder); err != nil {
+		return
+	}
+
+	// Remove
+	var checked bool
+	for _, name := range names {
+		if ok, _ := path.Match(pattern, name); !ok {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if f.TrashDir != "" && !checked {
+			f.checkFolders(conn, f.TrashDir)
+			checked = true
+		}
+		if err = f.discard(conn, path.Join(folder, name)); err != nil {
+			return
+		}
+		n++
+	}
+	return
+}
+
+// PurgeTrash deletes the files that were moved to the trash folder more than olderThan ago, and returns how many were
+// deleted. Files of the trash folder that were not moved by the client are left alone
+func (f *FTP) PurgeTrash(ctx context.Context, olderThan time.Duration) (n int, err error) {
+	// Log
+	l := fmt.Sprintf("FTP purge of %s older than %s", f.TrashDir, olderThan)
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	if f.TrashDir == "" {
+		return 0, ErrNoTrash
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	// List
+	var names []string
+	if names, err = f.fileNames(conn, f.TrashDir); err != nil {
+		return
+	}
+
+	// Delete
+	limit := time.Now().Add(-olderThan)
+	for _, name := range names {
+		if t, ok := trashTime(name); !ok || !t.Before(limit) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		p := path.Join(f.TrashDir, name)
+		log.Debugf("Deleting %s", p)
+		if err = conn.Delete(p); err != nil {
+			return
+		}
+		n++
+	}
+	return
+}
+
+// fileNames returns the sorted names of the files of a remote folder, a folder that doesn't exist having no files
+func (f *FTP) fileNames(conn ServerConnexion, dir string) (names []string, err error) {
+	files, err := f.listFiles(conn, dir)
+	if err != nil {
+		return
+	}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
@@ -0,0 +1,68 @@
+package ftp_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_RemoveGlob(t *testing.T) {
+	files := []*base.Entry{
+		{Name: "a.mp4", Type: base.EntryTypeFile},
+		{Name: "b.mp4", Type: base.EntryTypeFile},
+		{Name: "c.xml", Type: base.EntryTypeFile},
+		{Name: "d.mp4", Type: base.EntryTypeFolder},
+	}
+
+	// Delete
+	oConnexion := getMockOfServerConnexion(files).(*mocks.ServerConnexion)
+	oConnexion.On("Delete", mock.Anything).Return(nil)
+	n, err := NewFtp(oConnexion).RemoveGlob(context.Background(), "/in", "*.mp4")
+	if err != nil || n != 2 {
+		t.Fatalf("base.RemoveGlob() = %d, %v, want 2, nil", n, err)
+	}
+	oConnexion.AssertCalled(t, "Delete", "/in/a.mp4")
+	oConnexion.AssertCalled(t, "Delete", "/in/b.mp4")
+
+	// Trash
+	oConnexion = getMockOfServerConnexion(files).(*mocks.ServerConnexion)
+	oConnexion.On("FileSize", "/trash").Return(int64(0), nil)
+	oConnexion.On("Rename", mock.Anything, mock.Anything).Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+	if n, err = ftp.New(ftp.Configuration{TrashDir: "/trash"}, oDialer).RemoveGlob(context.Background(), "/in", "*.mp4"); err != nil || n != 2 {
+		t.Fatalf("base.RemoveGlob() = %d, %v, want 2, nil", n, err)
+	}
+	oConnexion.AssertNotCalled(t, "Delete", mock.Anything)
+	re := regexp.MustCompile(`^/trash/\d{8}T\d{6}\.\d{9}Z_in%2Fa\.mp4$`)
+	oConnexion.AssertCalled(t, "Rename", "/in/a.mp4", mock.MatchedBy(re.MatchString))
+	oConnexion.AssertNumberOfCalls(t, "FileSize", 1)
+}
+
+func TestFTP_PurgeTrash(t *testing.T) {
+	const layout = "20060102T150405.000000000Z"
+	oConnexion := getMockOfServerConnexion([]*base.Entry{
+		{Name: time.Now().Add(-48*time.Hour).UTC().Format(layout) + "_old.mp4", Type: base.EntryTypeFile},
+		{Name: time.Now().Add(-time.Hour).UTC().Format(layout) + "_recent.mp4", Type: base.EntryTypeFile},
+		{Name: "foreign.mp4", Type: base.EntryTypeFile},
+	}).(*mocks.ServerConnexion)
+	oConnexion.On("Delete", mock.Anything).Return(nil)
+	oDialer := &mocks.Dialer{}
+	oDialer.On("Dial", mock.Anything).Return(oConnexion, nil)
+
+	n, err := ftp.New(ftp.Configuration{TrashDir: "/trash"}, oDialer).PurgeTrash(context.Background(), 24*time.Hour)
+	if err != nil || n != 1 {
+		t.Fatalf("base.PurgeTrash() = %d, %v, want 1, nil", n, err)
+	}
+	oConnexion.AssertNumberOfCalls(t, "Delete", 1)
+
+	if _, err = NewFtp(oConnexion).PurgeTrash(context.Background(), time.Hour); err != ftp.ErrNoTrash {
+		t.Errorf("base.PurgeTrash() without trash error = %v, want %v", err, ftp.ErrNoTrash)
+	}
+}
@@ -0,0 +1,205 @@
+package ftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/molotovtv/go-logger"
+)
+
+// ringBuffer is a bounded in-memory pipe: writes block while it's full and reads block while it's empty
+type ringBuffer struct {
+	buf      []byte
+	c        *sync.Cond
+	errRead  error
+	errWrite error
+	m        sync.Mutex
+	n        int
+	r        int
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	b := &ringBuffer{buf: make([]byte, size)}
+	b.c = sync.NewCond(&b.m)
+	return b
+}
+
+// Write implements the io.Writer interface
+func (b *ringBuffer) Write(p []byte) (n int, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	for len(p) > 0 {
+		// Wait for some room
+		for b.n == len(b.buf) && b.errRead == nil && b.errWrite == nil {
+			b.c.Wait()
+		}
+		if b.errRead != nil {
+			return n, b.errRead
+		} else if b.errWrite != nil {
+			return n, io.ErrClosedPipe
+		}
+
+		// Copy
+		w := (b.r + b.n) % len(b.buf)
+		end := len(b.buf)
+		if w < b.r {
+			end = b.r
+		}
+		c := copy(b.buf[w:end], p)
+		b.n += c
+		n += c
+		p = p[c:]
+		b.c.Broadcast()
+	}
+	return
+}
+
+// Read implements the io.Reader interface
+func (b *ringBuffer) Read(p []byte) (n int, err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// Wait for some data
+	for b.n == 0 && b.errRead == nil && b.errWrite == nil {
+		b.c.Wait()
+	}
+	if b.errRead != nil {
+		return 0, b.errRead
+	} else if b.n == 0 {
+		return 0, b.errWrite
+	}
+
+	// Copy
+	end := b.r + b.n
+	if end > len(b.buf) {
+		end = len(b.buf)
+	}
+	n = copy(p, b.buf[b.r:end])
+	b.r = (b.r + n) % len(b.buf)
+	b.n -= n
+	b.c.Broadcast()
+	return
+}
+
+// closeWrite makes reads return err once the buffer is drained, io.EOF if err is nil
+func (b *ringBuffer) closeWrite(err error) {
+	if err == nil {
+		err = io.EOF
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.errWrite == nil {
+		b.errWrite = err
+	}
+	b.c.Broadcast()
+}
+
+// closeRead makes both reads and writes fail with err right away
+func (b *ringBuffer) closeRead(err error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	if b.errRead == nil {
+		b.errRead = err
+	}
+	b.c.Broadcast()
+}
+
+// uploadWriter streams what is written to it to a remote file through a ring buffer
+type uploadWriter struct {
+	b      *ringBuffer
+	cancel context.CancelFunc
+	done   chan error
+	o      sync.Once
+	err    error
+}
+
+// Write implements the io.Writer interface
+func (w *uploadWriter) Write(p []byte) (int, error) {
+	return w.b.Write(p)
+}
+
+// Close implements the io.Closer interface and waits for the transfer to be over
+func (w *uploadWriter) Close() error {
+	w.o.Do(func() {
+		w.b.closeWrite(nil)
+		w.err = <-w.done
+		w.cancel()
+	})
+	return w.err
+}
+
+// spillWriter writes to a temporary file and uploads it once closed
+type spillWriter struct {
+	*os.File
+	ctx  context.Context
+	dst  string
+	err  error
+	f    *FTP
+	o    sync.Once
+	opts []TransferOption
+}
+
+// Close implements the io.Closer interface and uploads the temporary file
+func (w *spillWriter) Close() error {
+	w.o.Do(func() {
+		defer os.Remove(w.Name())
+		defer w.File.Close()
+		if _, w.err = w.Seek(0, io.SeekStart); w.err != nil {
+			return
+		}
+		w.err = w.f.UploadReader(w.ctx, w.File, w.dst, w.opts...)
+	})
+	return w.err
+}
+
+// UploadWriter returns a writer whose content is streamed to a remote file, for sources of unknown length
+// Writes block while the internal buffer is full, which throttles the producer to the transfer speed, and fail as
+// soon as the transfer fails. Close must be called to finish the transfer and returns its error
+func (f *FTP) UploadWriter(ctx context.Context, dst string, opts ...TransferOption) (io.WriteCloser, error) {
+	o := newTransferOptions(opts)
+
+	// Spill
+	if o.spill {
+		tmp, err := ioutil.TempFile("", "go-ftp-")
+		if err != nil {
+			return nil, err
+		}
+		return &spillWriter{File: tmp, ctx: ctx, dst: dst, f: f, opts: opts}, nil
+	}
+
+	// Stream
+	ctx, cancel := context.WithCancel(ctx)
+	w := &uploadWriter{
+		b:      newRingBuffer(o.bufferSize),
+		cancel: cancel,
+		done:   make(chan error, 1),
+	}
+	go func() {
+		<-ctx.Done()
+		w.b.closeRead(ctx.Err())
+	}()
+	go func() {
+		// Log
+		l := fmt.Sprintf("FTP streamed upload to %s", dst)
+		log.Debugf("[Start] %s", l)
+		defer func(now time.Time) {
+			log.Debugf("[End] %s in %s", l, time.Since(now))
+		}(time.Now())
+
+		err := f.UploadReader(ctx, w.b, dst, opts...)
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			// Unblock the producer
+			w.b.closeRead(err)
+		}
+		w.done <- err
+	}()
+	return w, nil
+}
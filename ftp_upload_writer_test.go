@@ -0,0 +1,76 @@
+package ftp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFTP_UploadWriter(t *testing.T) {
+	var got bytes.Buffer
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Stor", "live.ts", mock.Anything).Return(func(path string, r io.Reader) error {
+		_, err := io.Copy(&got, r)
+		return err
+	})
+	oFtp := NewFtp(oConnexion)
+
+	w, err := oFtp.UploadWriter(context.Background(), "live.ts", ftp.WithBufferSize(7))
+	if err != nil {
+		t.Fatalf("base.UploadWriter() error = %v", err)
+	}
+	want := bytes.Repeat([]byte("0123456789"), 100)
+	for i := 0; i < len(want); i += 33 {
+		end := i + 33
+		if end > len(want) {
+			end = len(want)
+		}
+		if _, err = w.Write(want[i:end]); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Errorf("uploaded %d bytes, want %d", got.Len(), len(want))
+	}
+}
+
+func TestFTP_UploadWriter_Options(t *testing.T) {
+	for _, spill := range []bool{false, true} {
+		var got bytes.Buffer
+		oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+		oConnexion.On("Stor", "live.ts", mock.Anything).Return(func(path string, r io.Reader) error {
+			_, err := io.Copy(&got, r)
+			return err
+		})
+
+		// Options are forwarded to the upload
+		var progress int64
+		opts := []ftp.TransferOption{
+			ftp.WithProgress(func(n int64) { progress = n }),
+			ftp.WithReadTransform(func(r io.Reader) io.Reader { return io.MultiReader(r, strings.NewReader("!")) }),
+		}
+		if spill {
+			opts = append(opts, ftp.WithSpillToFile())
+		}
+		w, err := NewFtp(oConnexion).UploadWriter(context.Background(), "live.ts", opts...)
+		if err != nil {
+			t.Fatalf("base.UploadWriter() error = %v", err)
+		}
+		w.Write([]byte("content"))
+		if err = w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if got.String() != "content!" || progress != 8 {
+			t.Errorf("spill %v: uploaded %q with progress %d, want %q and 8", spill, got.String(), progress, "content!")
+		}
+	}
+}
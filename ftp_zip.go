@@ -0,0 +1,107 @@
+package ftp
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	log "github.com/molotovtv/go-logger"
+)
+
+// DownloadZip downloads several files into a single zip archive written to w, as they're downloaded
+// Files are stored with their cleaned path stripped of its leading slash so that files with the same name in
+// different folders don't collide, and with their modification time according to the listing of their folder
+// Relative paths going above the working directory fail, since they would be extracted outside the target folder
+func (f *FTP) DownloadZip(ctx context.Context, paths []string, w io.Writer, opts ...TransferOption) (err error) {
+	// Log
+	l := fmt.Sprintf("FTP zip download of %d files", len(paths))
+	log.Debugf("[Start] %s", l)
+	defer func(now time.Time) {
+		log.Debugf("[End] %s in %s", l, time.Since(now))
+	}(time.Now())
+
+	// Check names before anything is written
+	names := make([]string, len(paths))
+	for i, p := range paths {
+		if names[i], err = zipName(p); err != nil {
+			return
+		}
+	}
+
+	// Connect
+	var conn ServerConnexion
+	if conn, err = f.acquireContext(ctx); err != nil {
+		return
+	}
+	defer func() { f.release(conn, err) }()
+
+	o := newTransferOptions(opts)
+	zw := zip.NewWriter(w)
+	listings := make(map[string]map[string]*ftp.Entry)
+	for i, p := range paths {
+		// Check context error
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// Modification time, each folder being listed once
+		dir := path.Dir(p)
+		if _, ok := listings[dir]; !ok {
+			if listings[dir], err = f.listFiles(conn, dir); err != nil {
+				return
+			}
+		}
+		modified := time.Now()
+		if e, ok := listings[dir][path.Base(p)]; ok {
+			modified = e.Time
+		}
+
+		// Add file
+		if err = f.zipFile(ctx, conn, zw, p, names[i], modified, o); err != nil {
+			return
+		}
+	}
+	return zw.Close()
+}
+
+// zipName returns the name of the entry of a file, which can't point outside the folder the archive is extracted to
+func zipName(p string) (string, error) {
+	name := strings.TrimPrefix(path.Clean(p), "/")
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("ftp: invalid zip entry name %s", p)
+	}
+	return name, nil
+}
+
+func (f *FTP) zipFile(ctx context.Context, conn ServerConnexion, zw *zip.Writer, p, name string, modified time.Time, o transferOptions) (err error) {
+	// Download file
+	var r io.ReadCloser
+	log.Debugf("Downloading %s", p)
+	if r, err = f.retrFrom(conn, p, 0); err != nil {
+		return
+	}
+	defer func() {
+		if errClose := r.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	// Create entry
+	var zf io.Writer
+	if zf, err = zw.CreateHeader(&zip.FileHeader{
+		Method:   zip.Deflate,
+		Modified: modified,
+		Name:     name,
+	}); err != nil {
+		return
+	}
+
+	// Copy
+	_, err = copyContext(ctx, zf, r, o)
+	return
+}
@@ -0,0 +1,57 @@
+package ftp_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/textproto"
+	"testing"
+	"time"
+
+	base "github.com/jlaffaye/ftp"
+	ftp "github.com/molotovtv/go-ftp"
+	"github.com/molotovtv/go-ftp/mocks"
+)
+
+func TestFTP_DownloadZip_Error(t *testing.T) {
+	oConnexion := getMockOfServerConnexion(nil).(*mocks.ServerConnexion)
+	oConnexion.On("Retr", "/assets/missing.mp4").Return(nil, &textproto.Error{Code: base.StatusFileUnavailable, Msg: "No such file"})
+	oFtp := NewFtp(oConnexion)
+
+	var buf bytes.Buffer
+	if err := oFtp.DownloadZip(context.Background(), []string{"/assets/missing.mp4"}, &buf); err == nil {
+		t.Fatal("base.DownloadZip() should fail when a file can't be downloaded")
+	}
+}
+
+func TestFTP_DownloadZip(t *testing.T) {
+	modified := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Minute)
+	s := newFakeServer(t, map[string]string{"/assets/a.mp4": "a", "/assets/b/c.mp4": "c"})
+	s.modTime("/assets/a.mp4", modified)
+	oFtp := s.ftp(ftp.Configuration{})
+	defer oFtp.Close()
+
+	var buf bytes.Buffer
+	if err := oFtp.DownloadZip(context.Background(), []string{"/assets/a.mp4", "/assets/b/c.mp4"}, &buf); err != nil {
+		t.Fatalf("base.DownloadZip() error = %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("reading the archive error = %v", err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != "assets/a.mp4" || zr.File[1].Name != "assets/b/c.mp4" {
+		t.Fatalf("archive has %d entries, want assets/a.mp4 and assets/b/c.mp4", len(zr.File))
+	}
+	if m := zr.File[0].Modified; !m.Equal(modified) {
+		t.Errorf("entry modified at %s, want %s", m, modified)
+	}
+
+	// Paths going above the working directory are rejected before anything is written
+	buf.Reset()
+	if err = oFtp.DownloadZip(context.Background(), []string{"/assets/a.mp4", "../etc/passwd"}, &buf); err == nil {
+		t.Error("base.DownloadZip() with a path going up should fail")
+	}
+	if buf.Len() > 0 {
+		t.Errorf("%d bytes written, want none", buf.Len())
+	}
+}
@@ -3,9 +3,10 @@ module github.com/molotovtv/go-ftp
 go 1.16
 
 require (
+	github.com/ProtonMail/go-crypto v1.0.0
 	github.com/jlaffaye/ftp v0.0.0-20210307004419-5d4190119067
 	github.com/molotovtv/go-astilog v0.0.0-20190826120007-12ed50cb3050
-	github.com/molotovtv/go-astitools v0.0.0-20200810135017-6a70e61ed379
 	github.com/molotovtv/go-logger v0.0.0-20200814085816-66d58d12eeca
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
@@ -11,6 +11,55 @@ type ServerConnexion struct {
 	mock.Mock
 }
 
+// Append provides a mock function with given fields: path, oReader
+func (_m *ServerConnexion) Append(path string, oReader io.Reader) error {
+	ret := _m.Called(path, oReader)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, io.Reader) error); ok {
+		r0 = rf(path, oReader)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ChangeDir provides a mock function with given fields: sPath
+func (_m *ServerConnexion) ChangeDir(sPath string) error {
+	ret := _m.Called(sPath)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(sPath)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CurrentDir provides a mock function with given fields:
+func (_m *ServerConnexion) CurrentDir() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Delete provides a mock function with given fields: oath
 func (_m *ServerConnexion) Delete(oath string) error {
 	ret := _m.Called(oath)
@@ -25,6 +74,22 @@ func (_m *ServerConnexion) Delete(oath string) error {
 	return r0
 }
 
+// Features provides a mock function with given fields:
+func (_m *ServerConnexion) Features() map[string]string {
+	ret := _m.Called()
+
+	var r0 map[string]string
+	if rf, ok := ret.Get(0).(func() map[string]string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+
+	return r0
+}
+
 // FileSize provides a mock function with given fields: path
 func (_m *ServerConnexion) FileSize(path string) (int64, error) {
 	ret := _m.Called(path)
@@ -97,6 +162,20 @@ func (_m *ServerConnexion) MakeDir(sSource string) error {
 	return r0
 }
 
+// NoOp provides a mock function with given fields:
+func (_m *ServerConnexion) NoOp() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Quit provides a mock function with given fields:
 func (_m *ServerConnexion) Quit() error {
 	ret := _m.Called()
@@ -176,6 +255,29 @@ func (_m *ServerConnexion) Retr(path string) (*jlaffayeftp.Response, error) {
 	return r0, r1
 }
 
+// RetrFrom provides a mock function with given fields: path, offset
+func (_m *ServerConnexion) RetrFrom(path string, offset uint64) (*jlaffayeftp.Response, error) {
+	ret := _m.Called(path, offset)
+
+	var r0 *jlaffayeftp.Response
+	if rf, ok := ret.Get(0).(func(string, uint64) *jlaffayeftp.Response); ok {
+		r0 = rf(path, offset)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*jlaffayeftp.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, uint64) error); ok {
+		r1 = rf(path, offset)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Stor provides a mock function with given fields: path, oReader
 func (_m *ServerConnexion) Stor(path string, oReader io.Reader) error {
 	ret := _m.Called(path, oReader)
@@ -189,3 +291,17 @@ func (_m *ServerConnexion) Stor(path string, oReader io.Reader) error {
 
 	return r0
 }
+
+// StorFrom provides a mock function with given fields: path, oReader, offset
+func (_m *ServerConnexion) StorFrom(path string, oReader io.Reader, offset uint64) error {
+	ret := _m.Called(path, oReader, offset)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, io.Reader, uint64) error); ok {
+		r0 = rf(path, oReader, offset)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,106 @@
+// Package pgp encrypts uploads to recipient public keys and decrypts downloads with private keys, using OpenPGP
+// Content is streamed through the transforms of the FTP client and never buffered whole
+package pgp
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	ftp "github.com/molotovtv/go-ftp"
+)
+
+// Encrypt returns an upload option that encrypts the content to recipients
+// It comes after the compression of uploads and should be the last transform
+func Encrypt(recipients openpgp.EntityList) ftp.TransferOption {
+	return ftp.WithWriteTransform(func(w io.Writer) io.WriteCloser {
+		return encryptWriter(w, recipients)
+	})
+}
+
+// Decrypt returns a download option that decrypts the content with the private keys of keyring
+// The integrity of the content is only checked once it's been fully read, so a failing download may have written
+// some of it
+func Decrypt(keyring openpgp.KeyRing) ftp.TransferOption {
+	return ftp.WithReadTransform(func(r io.Reader) io.Reader {
+		return decryptReader(r, keyring)
+	})
+}
+
+// ReadKeyRing reads an armored or binary keyring and decrypts its private keys with passphrase, if any
+func ReadKeyRing(path string, passphrase []byte) (el openpgp.EntityList, err error) {
+	var b []byte
+	if b, err = ioutil.ReadFile(path); err != nil {
+		return
+	}
+	if _, errArmor := armor.Decode(bytes.NewReader(b)); errArmor == nil {
+		el, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	} else {
+		el, err = openpgp.ReadKeyRing(bytes.NewReader(b))
+	}
+	if err != nil || len(passphrase) == 0 {
+		return
+	}
+	for _, e := range el {
+		if e.PrivateKey != nil && e.PrivateKey.Encrypted {
+			if err = e.PrivateKey.Decrypt(passphrase); err != nil {
+				return
+			}
+		}
+		for _, s := range e.Subkeys {
+			if s.PrivateKey != nil && s.PrivateKey.Encrypted {
+				if err = s.PrivateKey.Decrypt(passphrase); err != nil {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+func encryptWriter(w io.Writer, recipients openpgp.EntityList) io.WriteCloser {
+	if len(recipients) == 0 {
+		return errWriter{errors.New("pgp: no recipients")}
+	}
+	wc, err := openpgp.Encrypt(w, recipients, nil, nil, nil)
+	if err != nil {
+		return errWriter{err}
+	}
+	return wc
+}
+
+func decryptReader(r io.Reader, keyring openpgp.KeyRing) io.Reader {
+	md, err := openpgp.ReadMessage(r, keyring, nil, nil)
+	if err != nil {
+		return errReader{err}
+	}
+	return md.UnverifiedBody
+}
+
+// errWriter fails writes when the encryption couldn't be set up
+type errWriter struct {
+	err error
+}
+
+// Write implements the io.Writer interface
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+// Close implements the io.Closer interface
+func (w errWriter) Close() error {
+	return w.err
+}
+
+// errReader fails reads when the decryption couldn't be set up
+type errReader struct {
+	err error
+}
+
+// Read implements the io.Reader interface
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
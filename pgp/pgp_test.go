@@ -0,0 +1,57 @@
+package pgp
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// newEntity generates a small key so that tests stay fast
+func newEntity(t *testing.T, name string) *openpgp.Entity {
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{RSABits: 1024})
+	if err != nil {
+		t.Fatalf("openpgp.NewEntity() error = %v", err)
+	}
+	return e
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	e := newEntity(t, "test")
+	plain := bytes.Repeat([]byte("content"), 10000)
+
+	// Encrypt
+	var enc bytes.Buffer
+	w := encryptWriter(&enc, openpgp.EntityList{e})
+	if _, err := w.Write(plain); err != nil {
+		t.Fatalf("encrypting error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("closing the encryption error = %v", err)
+	}
+	if bytes.Contains(enc.Bytes(), []byte("content")) {
+		t.Error("encrypted content contains the plaintext")
+	}
+
+	// Decrypt
+	got, err := ioutil.ReadAll(decryptReader(bytes.NewReader(enc.Bytes()), openpgp.EntityList{e}))
+	if err != nil {
+		t.Fatalf("decrypting error = %v", err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("decrypted %d bytes, want the plaintext", len(got))
+	}
+
+	// Unknown key
+	other := newEntity(t, "other")
+	if _, err = ioutil.ReadAll(decryptReader(bytes.NewReader(enc.Bytes()), openpgp.EntityList{other})); err == nil {
+		t.Error("decrypting with an unknown key error = nil")
+	}
+
+	// No recipients
+	if _, err = encryptWriter(&enc, nil).Write(plain); err == nil {
+		t.Error("encrypting without recipients error = nil")
+	}
+}